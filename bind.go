@@ -0,0 +1,304 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Unmarshal populates the struct pointed to by v from environment variables.
+// Each field tagged `env:"KEY"` is set from KEY when it is present; fields
+// whose variable is absent keep their current value. Nested struct fields are
+// bound recursively, and a tag on a struct field becomes a prefix for its
+// fields, e.g. `env:"DB"` binds an inner `env:"HOST"` field from DB_HOST or,
+// if that is absent, from the dotted DB.HOST as written in some .env files.
+// A tagged slice of structs binds one element per index, e.g. `env:"SERVERS"`
+// reads SERVERS_0_HOST, SERVERS_1_HOST and so on until an index is missing.
+// A tagged map of strings to structs binds one entry per name, e.g.
+// `env:"TENANTS"` reads TENANTS_acme_PLAN into the "acme" entry; names end at
+// the first underscore.
+// Supported field types are strings, booleans, integers, unsigned integers,
+// floats, time.Duration and slices of those, split on DefaultDelimiter.
+// Parse failures are collected and returned together. If v implements
+// Validator and binding succeeded, its Validate method is called and its
+// error returned.
+func Unmarshal(v interface{}) error {
+	rv, err := structPointer(v, "v")
+	if err != nil {
+		return err
+	}
+	return validateBound(v, bindStruct(rv, reflect.Value{}, "", ""))
+}
+
+// Validator is implemented by config types that check themselves after
+// Unmarshal or UnmarshalWithDefaults has populated them.
+type Validator interface {
+	Validate() error
+}
+
+// validateBound joins the binding errors of v or, if there are none, runs
+// its Validate method when it implements Validator.
+func validateBound(v interface{}, errs []error) error {
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	if validator, ok := v.(Validator); ok {
+		return validator.Validate()
+	}
+	return nil
+}
+
+// UnmarshalWithDefaults works like Unmarshal, but fields whose variable is
+// absent are filled from the corresponding field of defaults, which must be a
+// struct (or pointer to a struct) of the same type as the one v points to.
+// This allows defaults to be built programmatically instead of with tags.
+func UnmarshalWithDefaults(v interface{}, defaults interface{}) error {
+	rv, err := structPointer(v, "v")
+	if err != nil {
+		return err
+	}
+	dv := reflect.ValueOf(defaults)
+	if dv.Kind() == reflect.Pointer && !dv.IsNil() {
+		dv = dv.Elem()
+	}
+	if !dv.IsValid() || dv.Type() != rv.Type() {
+		return fmt.Errorf("env: defaults must be a %s, got %T", rv.Type(), defaults)
+	}
+	return validateBound(v, bindStruct(rv, dv, "", ""))
+}
+
+// structPointer returns the struct value v points to.
+func structPointer(v interface{}, name string) (reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("env: %s must be a non-nil pointer to a struct, got %T", name, v)
+	}
+	return rv.Elem(), nil
+}
+
+// durationType is the reflected type of time.Duration.
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// fieldPlan describes how bindStruct handles one struct field.
+type fieldPlan struct {
+	index       int
+	tag         string
+	tagged      bool
+	nested      bool
+	structSlice bool
+	structMap   bool
+}
+
+// bindPlans caches the []fieldPlan of each struct type, keyed by reflect.Type,
+// so repeated binds of the same type skip re-reading fields and tags.
+var bindPlans sync.Map
+
+// planFor returns the cached binding plan for struct type rt, building it on
+// first use. Unexported fields and untagged non-struct fields are omitted.
+func planFor(rt reflect.Type) []fieldPlan {
+	if plan, ok := bindPlans.Load(rt); ok {
+		return plan.([]fieldPlan)
+	}
+	var plan []fieldPlan
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag, tagged := field.Tag.Lookup("env")
+		nested := field.Type.Kind() == reflect.Struct && field.Type != durationType
+		if !tagged && !nested {
+			continue
+		}
+		structSlice := field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Struct
+		structMap := field.Type.Kind() == reflect.Map && field.Type.Key().Kind() == reflect.String &&
+			field.Type.Elem().Kind() == reflect.Struct
+		plan = append(plan, fieldPlan{
+			index:       i,
+			tag:         tag,
+			tagged:      tagged,
+			nested:      nested,
+			structSlice: structSlice,
+			structMap:   structMap,
+		})
+	}
+	actual, _ := bindPlans.LoadOrStore(rt, plan)
+	return actual.([]fieldPlan)
+}
+
+// bindStruct sets the tagged fields of rv from variables named prefix+tag or,
+// if those are absent and dotted is not empty, dotted+tag. When dv is valid,
+// absent variables take their value from the same field of dv.
+func bindStruct(rv, dv reflect.Value, prefix, dotted string) []error {
+	var errs []error
+	for _, field := range planFor(rv.Type()) {
+		fv := rv.Field(field.index)
+		var fd reflect.Value
+		if dv.IsValid() {
+			fd = dv.Field(field.index)
+		}
+
+		// Nested structs bind their own fields, prefixed by the tag if any
+		if field.nested {
+			nested, nestedDotted := prefix, dotted
+			if field.tagged {
+				nested = prefix + field.tag + "_"
+				nestedDotted = dotted + field.tag + "."
+			}
+			errs = append(errs, bindStruct(fv, fd, nested, nestedDotted)...)
+			continue
+		}
+
+		// Struct slices bind one element per index, e.g. SERVERS_0_HOST
+		if field.structSlice {
+			errs = append(errs, bindStructSlice(fv, fd, prefix+field.tag+"_")...)
+			continue
+		}
+
+		// Struct maps bind one entry per name, e.g. TENANTS_acme_PLAN
+		if field.structMap {
+			errs = append(errs, bindStructMap(fv, fd, prefix+field.tag+"_")...)
+			continue
+		}
+
+		key := prefix + field.tag
+		val := GetEnvString(key, "")
+		if val == "" && dotted != "" {
+			key = dotted + field.tag
+			val = GetEnvString(key, "")
+		}
+		if val == "" {
+			if fd.IsValid() {
+				fv.Set(fd)
+			}
+			continue
+		}
+		if err := setField(fv, val); err != nil {
+			errs = append(errs, fmt.Errorf("environment variable %s: %w", key, err))
+		}
+	}
+	return errs
+}
+
+// bindStructSlice sets the slice of structs fv from variables named
+// prefix+index+"_"+tag, counting up from index 0 until an index has no
+// variables. If none are found, fv keeps its value or takes it from fd.
+func bindStructSlice(fv, fd reflect.Value, prefix string) []error {
+	env := environ()
+	var errs []error
+	slice := reflect.MakeSlice(fv.Type(), 0, 0)
+	for i := 0; hasPrefix(env, prefix+strconv.Itoa(i)+"_"); i++ {
+		elem := reflect.New(fv.Type().Elem()).Elem()
+		errs = append(errs, bindStruct(elem, reflect.Value{}, prefix+strconv.Itoa(i)+"_", "")...)
+		slice = reflect.Append(slice, elem)
+	}
+	if slice.Len() > 0 {
+		fv.Set(slice)
+	} else if fd.IsValid() {
+		fv.Set(fd)
+	}
+	return errs
+}
+
+// bindStructMap sets entries of the map of structs fv from variables named
+// prefix+name+"_"+tag, where name runs up to the next underscore and becomes
+// the map key. Existing entries are updated in place. If no variables are
+// found, fv keeps its value or takes it from fd.
+func bindStructMap(fv, fd reflect.Value, prefix string) []error {
+	names := make(map[string]bool)
+	for key := range environ() {
+		if rest, ok := strings.CutPrefix(key, prefix); ok {
+			if name, _, ok := strings.Cut(rest, "_"); ok && name != "" {
+				names[name] = true
+			}
+		}
+	}
+	if len(names) == 0 {
+		if fd.IsValid() {
+			fv.Set(fd)
+		}
+		return nil
+	}
+
+	if fv.IsNil() {
+		fv.Set(reflect.MakeMap(fv.Type()))
+	}
+	var errs []error
+	for name := range names {
+		key := reflect.ValueOf(name).Convert(fv.Type().Key())
+		elem := reflect.New(fv.Type().Elem()).Elem()
+		if existing := fv.MapIndex(key); existing.IsValid() {
+			elem.Set(existing)
+		}
+		errs = append(errs, bindStruct(elem, reflect.Value{}, prefix+name+"_", "")...)
+		fv.SetMapIndex(key, elem)
+	}
+	return errs
+}
+
+// hasPrefix reports whether any key of env starts with prefix.
+func hasPrefix(env map[string]string, prefix string) bool {
+	for key := range env {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// setField parses val into fv according to its type.
+func setField(fv reflect.Value, val string) error {
+	if fv.Type() == durationType {
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(val)
+	case reflect.Bool:
+		b, err := parseBool(val)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(val, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(val, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(val, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	case reflect.Slice:
+		parts := strings.Split(val, DefaultDelimiter)
+		slice := reflect.MakeSlice(fv.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			if err := setField(slice.Index(i), strings.TrimSpace(part)); err != nil {
+				return fmt.Errorf("element %d: %w", i, err)
+			}
+		}
+		fv.Set(slice)
+	default:
+		return fmt.Errorf("unsupported field type %s", fv.Type())
+	}
+	return nil
+}
@@ -0,0 +1,299 @@
+package env
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+    "time"
+)
+
+type bindDBConfig struct {
+    Host string `env:"HOST"`
+    Port int    `env:"PORT"`
+}
+
+type bindConfig struct {
+    Name    string        `env:"TEST_BIND_NAME"`
+    Debug   bool          `env:"TEST_BIND_DEBUG"`
+    Timeout time.Duration `env:"TEST_BIND_TIMEOUT"`
+    Ratio   float64       `env:"TEST_BIND_RATIO"`
+    Tags    []string      `env:"TEST_BIND_TAGS"`
+    DB      bindDBConfig  `env:"TEST_BIND_DB"`
+    Ignored string
+}
+
+// Test for binding environment variables into a struct
+func TestUnmarshal(t *testing.T) {
+    os.Setenv("TEST_BIND_NAME", "svc")
+    os.Setenv("TEST_BIND_DEBUG", "true")
+    os.Setenv("TEST_BIND_TIMEOUT", "5s")
+    os.Setenv("TEST_BIND_TAGS", "a,b")
+    os.Setenv("TEST_BIND_DB_HOST", "db.local")
+    defer os.Unsetenv("TEST_BIND_NAME")
+    defer os.Unsetenv("TEST_BIND_DEBUG")
+    defer os.Unsetenv("TEST_BIND_TIMEOUT")
+    defer os.Unsetenv("TEST_BIND_TAGS")
+    defer os.Unsetenv("TEST_BIND_DB_HOST")
+
+    cfg := bindConfig{Ratio: 0.5, DB: bindDBConfig{Port: 5432}}
+    if err := Unmarshal(&cfg); err != nil {
+        t.Fatalf("Unmarshal failed: %v", err)
+    }
+
+    // Absent variables keep the existing field values
+    want := bindConfig{
+        Name:    "svc",
+        Debug:   true,
+        Timeout: 5 * time.Second,
+        Ratio:   0.5,
+        Tags:    []string{"a", "b"},
+        DB:      bindDBConfig{Host: "db.local", Port: 5432},
+    }
+    if !reflect.DeepEqual(cfg, want) {
+        t.Errorf("got %+v; want %+v", cfg, want)
+    }
+
+    // Parse failures are reported
+    os.Setenv("TEST_BIND_DB_PORT", "not-a-port")
+    defer os.Unsetenv("TEST_BIND_DB_PORT")
+    if err := Unmarshal(&cfg); err == nil {
+        t.Errorf("expected error for invalid port")
+    }
+
+    if err := Unmarshal(cfg); err == nil {
+        t.Errorf("expected error for non-pointer argument")
+    }
+}
+
+// Test for filling absent fields from a defaults struct
+func TestUnmarshalWithDefaults(t *testing.T) {
+    os.Setenv("TEST_BIND_NAME", "from-env")
+    os.Setenv("TEST_BIND_DB_PORT", "6543")
+    defer os.Unsetenv("TEST_BIND_NAME")
+    defer os.Unsetenv("TEST_BIND_DB_PORT")
+
+    defaults := bindConfig{
+        Name:    "from-defaults",
+        Timeout: time.Minute,
+        DB:      bindDBConfig{Host: "localhost", Port: 5432},
+    }
+
+    var cfg bindConfig
+    if err := UnmarshalWithDefaults(&cfg, defaults); err != nil {
+        t.Fatalf("UnmarshalWithDefaults failed: %v", err)
+    }
+
+    // Env-set fields win; the rest come from the defaults
+    want := bindConfig{
+        Name:    "from-env",
+        Timeout: time.Minute,
+        DB:      bindDBConfig{Host: "localhost", Port: 6543},
+    }
+    if !reflect.DeepEqual(cfg, want) {
+        t.Errorf("got %+v; want %+v", cfg, want)
+    }
+
+    // Defaults of a different type are rejected
+    if err := UnmarshalWithDefaults(&cfg, bindDBConfig{}); err == nil {
+        t.Errorf("expected error for mismatched defaults type")
+    }
+
+    // Nil defaults are rejected rather than panicking
+    if err := UnmarshalWithDefaults(&cfg, nil); err == nil {
+        t.Errorf("expected error for nil defaults")
+    }
+    if err := UnmarshalWithDefaults(&cfg, (*bindConfig)(nil)); err == nil {
+        t.Errorf("expected error for a nil defaults pointer")
+    }
+}
+
+// Test for binding a slice of structs from indexed variables
+func TestUnmarshalStructSlice(t *testing.T) {
+    type servers struct {
+        Servers []bindDBConfig `env:"TEST_BIND_SERVERS"`
+    }
+    os.Setenv("TEST_BIND_SERVERS_0_HOST", "a.local")
+    os.Setenv("TEST_BIND_SERVERS_0_PORT", "80")
+    os.Setenv("TEST_BIND_SERVERS_1_HOST", "b.local")
+    defer os.Unsetenv("TEST_BIND_SERVERS_0_HOST")
+    defer os.Unsetenv("TEST_BIND_SERVERS_0_PORT")
+    defer os.Unsetenv("TEST_BIND_SERVERS_1_HOST")
+
+    // Index 3 is unreachable because index 2 is missing
+    os.Setenv("TEST_BIND_SERVERS_3_HOST", "d.local")
+    defer os.Unsetenv("TEST_BIND_SERVERS_3_HOST")
+
+    var cfg servers
+    if err := Unmarshal(&cfg); err != nil {
+        t.Fatalf("Unmarshal failed: %v", err)
+    }
+    want := []bindDBConfig{{Host: "a.local", Port: 80}, {Host: "b.local"}}
+    if !reflect.DeepEqual(cfg.Servers, want) {
+        t.Errorf("got %+v; want %+v", cfg.Servers, want)
+    }
+
+    // Element parse failures are reported with the full key
+    os.Setenv("TEST_BIND_SERVERS_1_PORT", "http")
+    defer os.Unsetenv("TEST_BIND_SERVERS_1_PORT")
+    if err := Unmarshal(&cfg); err == nil || !strings.Contains(err.Error(), "TEST_BIND_SERVERS_1_PORT") {
+        t.Errorf("got %v; want error naming TEST_BIND_SERVERS_1_PORT", err)
+    }
+}
+
+// Test for binding a map of structs from named variables
+func TestUnmarshalStructMap(t *testing.T) {
+    type tenant struct {
+        Plan  string `env:"PLAN"`
+        Seats int    `env:"SEATS"`
+    }
+    type tenants struct {
+        Tenants map[string]tenant `env:"TEST_BIND_TENANTS"`
+    }
+    os.Setenv("TEST_BIND_TENANTS_acme_PLAN", "pro")
+    os.Setenv("TEST_BIND_TENANTS_acme_SEATS", "25")
+    os.Setenv("TEST_BIND_TENANTS_globex_PLAN", "free")
+    defer os.Unsetenv("TEST_BIND_TENANTS_acme_PLAN")
+    defer os.Unsetenv("TEST_BIND_TENANTS_acme_SEATS")
+    defer os.Unsetenv("TEST_BIND_TENANTS_globex_PLAN")
+
+    // Existing entries keep fields without a variable
+    cfg := tenants{Tenants: map[string]tenant{"globex": {Seats: 3}}}
+    if err := Unmarshal(&cfg); err != nil {
+        t.Fatalf("Unmarshal failed: %v", err)
+    }
+    want := map[string]tenant{
+        "acme":   {Plan: "pro", Seats: 25},
+        "globex": {Plan: "free", Seats: 3},
+    }
+    if !reflect.DeepEqual(cfg.Tenants, want) {
+        t.Errorf("got %+v; want %+v", cfg.Tenants, want)
+    }
+
+    // Without any variables the map is left untouched
+    var empty struct {
+        Tenants map[string]tenant `env:"TEST_BIND_NO_TENANTS"`
+    }
+    if err := Unmarshal(&empty); err != nil || empty.Tenants != nil {
+        t.Errorf("got (%v, %v); want nil map and no error", empty.Tenants, err)
+    }
+}
+
+// Test for binding dotted keys into nested structs
+func TestUnmarshalDottedKeys(t *testing.T) {
+    type server struct {
+        DB   bindDBConfig `env:"DB"`
+        Name string       `env:"NAME"`
+    }
+    type dotted struct {
+        Server server `env:"TEST_DOT"`
+    }
+    dir := t.TempDir()
+    defer Load(envDir)
+
+    os.WriteFile(filepath.Join(dir, "app.env"), []byte(
+        "TEST_DOT.DB.HOST=db.local\nTEST_DOT.DB.PORT=5432\nTEST_DOT.NAME=dotted\nTEST_DOT_NAME=underscored\n"), 0o644)
+    Load(dir)
+
+    var cfg dotted
+    if err := Unmarshal(&cfg); err != nil {
+        t.Fatalf("Unmarshal failed: %v", err)
+    }
+
+    // Two-level dotted keys bind, and underscore keys take precedence
+    want := dotted{Server: server{DB: bindDBConfig{Host: "db.local", Port: 5432}, Name: "underscored"}}
+    if !reflect.DeepEqual(cfg, want) {
+        t.Errorf("got %+v; want %+v", cfg, want)
+    }
+}
+
+type bindValidatedConfig struct {
+    Port int `env:"TEST_BIND_VALIDATED_PORT"`
+}
+
+func (c *bindValidatedConfig) Validate() error {
+    if c.Port < 1024 {
+        return fmt.Errorf("port %d is privileged", c.Port)
+    }
+    return nil
+}
+
+// Test that Unmarshal calls Validate on types implementing Validator
+func TestUnmarshalValidate(t *testing.T) {
+    os.Setenv("TEST_BIND_VALIDATED_PORT", "8080")
+    defer os.Unsetenv("TEST_BIND_VALIDATED_PORT")
+
+    var cfg bindValidatedConfig
+    if err := Unmarshal(&cfg); err != nil {
+        t.Errorf("got %v; want nil", err)
+    }
+
+    os.Setenv("TEST_BIND_VALIDATED_PORT", "80")
+    if err := Unmarshal(&cfg); err == nil || err.Error() != "port 80 is privileged" {
+        t.Errorf("got %v; want validation error", err)
+    }
+    if err := UnmarshalWithDefaults(&cfg, bindValidatedConfig{}); err == nil {
+        t.Errorf("expected validation error from UnmarshalWithDefaults")
+    }
+
+    // Parse failures are returned without validating
+    os.Setenv("TEST_BIND_VALIDATED_PORT", "http")
+    if err := Unmarshal(&cfg); err == nil || !strings.Contains(err.Error(), "TEST_BIND_VALIDATED_PORT") {
+        t.Errorf("got %v; want parse error", err)
+    }
+}
+
+// Test that repeated binds reuse the cached plan and stay correct
+func TestUnmarshalPlanCache(t *testing.T) {
+    os.Setenv("TEST_BIND_NAME", "first")
+    defer os.Unsetenv("TEST_BIND_NAME")
+
+    var first bindConfig
+    if err := Unmarshal(&first); err != nil {
+        t.Fatalf("Unmarshal failed: %v", err)
+    }
+    if _, ok := bindPlans.Load(reflect.TypeOf(first)); !ok {
+        t.Errorf("expected plan to be cached after the first bind")
+    }
+
+    // A second bind with changed values uses the cached plan
+    os.Setenv("TEST_BIND_NAME", "second")
+    os.Setenv("TEST_BIND_DB_PORT", "1234")
+    defer os.Unsetenv("TEST_BIND_DB_PORT")
+    var second bindConfig
+    if err := Unmarshal(&second); err != nil {
+        t.Fatalf("Unmarshal failed: %v", err)
+    }
+    if second.Name != "second" || second.DB.Port != 1234 {
+        t.Errorf("got %+v; want Name=second and DB.Port=1234", second)
+    }
+}
+
+// Benchmark for repeatedly binding the same struct type
+func BenchmarkUnmarshal(b *testing.B) {
+    os.Setenv("TEST_BIND_NAME", "bench")
+    os.Setenv("TEST_BIND_DB_PORT", "5432")
+    defer os.Unsetenv("TEST_BIND_NAME")
+    defer os.Unsetenv("TEST_BIND_DB_PORT")
+
+    var cfg bindConfig
+    for i := 0; i < b.N; i++ {
+        Unmarshal(&cfg)
+    }
+}
+
+// Benchmark for binding with the plan cache cleared on every call
+func BenchmarkUnmarshalUncached(b *testing.B) {
+    os.Setenv("TEST_BIND_NAME", "bench")
+    os.Setenv("TEST_BIND_DB_PORT", "5432")
+    defer os.Unsetenv("TEST_BIND_NAME")
+    defer os.Unsetenv("TEST_BIND_DB_PORT")
+
+    var cfg bindConfig
+    for i := 0; i < b.N; i++ {
+        bindPlans.Clear()
+        Unmarshal(&cfg)
+    }
+}
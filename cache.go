@@ -0,0 +1,144 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// cacheEntry is a cached lookup result, including misses.
+type cacheEntry struct {
+	val string
+	ok  bool
+}
+
+var (
+	// cacheEnabled is checked before cacheMu is taken, so reads with the
+	// cache off (the default) never lock. It is only written under cacheMu.
+	cacheEnabled atomic.Bool
+	cache        map[string]cacheEntry
+	cacheMu      sync.RWMutex
+
+	// cacheGen counts invalidations, starting at 1. A result resolved under
+	// an older generation may predate the change that invalidated it and is
+	// not stored. Generation 0 marks a read made with the cache off.
+	cacheGen uint64 = 1
+)
+
+// EnableCache turns caching of resolved values on or off. While enabled, each
+// key is resolved from the OS environment and loaded files once and served
+// from memory afterwards, avoiding repeated lookups and decryption in hot
+// paths. The cache is cleared by Load, Reload, SetEnvPersistent,
+// SetEnvTransient and the config loaders, but changes made with os.Setenv
+// are not seen until then.
+func EnableCache(enabled bool) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	cacheEnabled.Store(enabled)
+	cacheGen++
+	cache = nil
+	if enabled {
+		cache = make(map[string]cacheEntry)
+	}
+}
+
+// invalidateCache drops all cached values, including those validated by
+// GetEnvStringLazy.
+func invalidateCache() {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	if cacheEnabled.Load() {
+		cache = make(map[string]cacheEntry)
+	}
+	cacheGen++
+	lazyValues = nil
+}
+
+// cacheGet returns the cached entry for key, if caching is enabled, and the
+// current generation to pass to cachePut on a miss. With the cache off it
+// returns generation 0 without locking.
+func cacheGet(key string) (cacheEntry, bool, uint64) {
+	if !cacheEnabled.Load() {
+		return cacheEntry{}, false, 0
+	}
+	cacheMu.RLock()
+	defer cacheMu.RUnlock()
+	entry, hit := cache[key]
+	return entry, hit, cacheGen
+}
+
+// cachePut stores entry for key, if caching is enabled and the cache has not
+// been invalidated since gen was returned by cacheGet.
+func cachePut(key string, entry cacheEntry, gen uint64) {
+	if gen == 0 || !cacheEnabled.Load() {
+		return
+	}
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	if cacheEnabled.Load() && gen == cacheGen {
+		cache[key] = entry
+	}
+}
+
+// lazyValues holds the results of GetEnvStringLazy by key. It is guarded by
+// cacheMu and cleared by invalidateCache.
+var lazyValues map[string]string
+
+// GetEnvStringLazy retrieves an environment variable's value as a string,
+// running validate on the first read only. The validated result is kept and
+// returned by later calls without validating again, until the next Load,
+// Reload or in-memory set. A value rejected by validate is reported through
+// the error handler (panicking by default) and the default is used instead.
+func GetEnvStringLazy(key string, validate func(string) error, defaultValue string) string {
+	cacheMu.RLock()
+	val, ok := lazyValues[key]
+	gen := cacheGen
+	cacheMu.RUnlock()
+	if ok {
+		return val
+	}
+
+	val = defaultValue
+	if resolved, ok := lookup(key); ok {
+		if err := validate(resolved); err != nil {
+			parseFailed(key, fmt.Errorf("Environment variable %s failed validation: %v", key, err))
+		} else {
+			val = resolved
+		}
+	}
+
+	// Keep the result only if nothing changed while it was resolved
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	if gen != cacheGen {
+		return val
+	}
+	if lazyValues == nil {
+		lazyValues = make(map[string]string)
+	}
+	lazyValues[key] = val
+	return val
+}
+
+// Prefetch resolves each of keys once, e.g. at startup, so later reads are
+// served from the cache when it is enabled. Values that cannot be decrypted
+// are reported through the error handler, as on a regular read.
+func Prefetch(keys ...string) {
+	for _, key := range keys {
+		lookup(key)
+	}
+}
+
+// PrefetchStrict is like Prefetch but returns the failures of all keys joined
+// into a single error instead of reporting them through the error handler.
+// Unset keys are not an error.
+func PrefetchStrict(keys ...string) error {
+	var errs []error
+	for _, key := range keys {
+		if _, _, err := resolve(key); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,258 @@
+package env
+
+import (
+    "errors"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
+    "testing"
+)
+
+// Test that cached values are invalidated by reloads and in-memory sets
+func TestEnableCache(t *testing.T) {
+    dir := t.TempDir()
+    defer Load(envDir)
+    defer Reset()
+
+    file := filepath.Join(dir, "app.env")
+    os.WriteFile(file, []byte("TEST_CACHE=v1\n"), 0o644)
+    Load(dir)
+    EnableCache(true)
+
+    if got := GetEnvString("TEST_CACHE", ""); got != "v1" {
+        t.Errorf("got %q; want %q", got, "v1")
+    }
+
+    // Cached values hide live OS changes until invalidated
+    os.Setenv("TEST_CACHE", "from-os")
+    defer os.Unsetenv("TEST_CACHE")
+    if got := GetEnvString("TEST_CACHE", ""); got != "v1" {
+        t.Errorf("got %q; want cached %q", got, "v1")
+    }
+    os.Unsetenv("TEST_CACHE")
+
+    // A reload invalidates the cache
+    os.WriteFile(file, []byte("TEST_CACHE=v2\n"), 0o644)
+    Reload()
+    if got := GetEnvString("TEST_CACHE", ""); got != "v2" {
+        t.Errorf("got %q after reload; want %q", got, "v2")
+    }
+
+    // So does setting a value in memory
+    SetEnvTransient("TEST_CACHE", "v3")
+    if got := GetEnvString("TEST_CACHE", ""); got != "v3" {
+        t.Errorf("got %q after SetEnvTransient; want %q", got, "v3")
+    }
+}
+
+// Test that Prefetch warms the cache for the given keys
+func TestPrefetch(t *testing.T) {
+    defer Reset()
+    EnableCache(true)
+
+    os.Setenv("TEST_PREFETCH", "warm")
+    defer os.Unsetenv("TEST_PREFETCH")
+    Prefetch("TEST_PREFETCH", "TEST_PREFETCH_MISSING")
+
+    if entry, hit, _ := cacheGet("TEST_PREFETCH"); !hit || entry.val != "warm" {
+        t.Errorf("got (%+v, %v); want cached %q", entry, hit, "warm")
+    }
+    if entry, hit, _ := cacheGet("TEST_PREFETCH_MISSING"); !hit || entry.ok {
+        t.Errorf("got (%+v, %v); want cached miss", entry, hit)
+    }
+
+    // Later reads are served from the cache
+    os.Setenv("TEST_PREFETCH", "changed")
+    if got := GetEnvString("TEST_PREFETCH", ""); got != "warm" {
+        t.Errorf("got %q; want cached %q", got, "warm")
+    }
+}
+
+// Test that PrefetchStrict aggregates failures instead of panicking
+func TestPrefetchStrict(t *testing.T) {
+    defer Reset()
+    RegisterDecryptor("enc:", func(s string) (string, error) {
+        return "", errors.New("bad ciphertext")
+    })
+
+    os.Setenv("TEST_PREFETCH_A", "enc:x")
+    os.Setenv("TEST_PREFETCH_B", "enc:y")
+    os.Setenv("TEST_PREFETCH_OK", "plain")
+    defer os.Unsetenv("TEST_PREFETCH_A")
+    defer os.Unsetenv("TEST_PREFETCH_B")
+    defer os.Unsetenv("TEST_PREFETCH_OK")
+
+    err := PrefetchStrict("TEST_PREFETCH_A", "TEST_PREFETCH_OK", "TEST_PREFETCH_B", "TEST_PREFETCH_MISSING")
+    if err == nil {
+        t.Fatalf("expected an error")
+    }
+    for _, key := range []string{"TEST_PREFETCH_A", "TEST_PREFETCH_B"} {
+        if !strings.Contains(err.Error(), key) {
+            t.Errorf("error %q does not mention %s", err, key)
+        }
+    }
+    if strings.Contains(err.Error(), "TEST_PREFETCH_OK") {
+        t.Errorf("error %q mentions a valid key", err)
+    }
+
+    if err := PrefetchStrict("TEST_PREFETCH_OK", "TEST_PREFETCH_MISSING"); err != nil {
+        t.Errorf("got %v; want nil", err)
+    }
+}
+
+// Test that GetEnvStringLazy validates once and then serves the kept value
+func TestGetEnvStringLazy(t *testing.T) {
+    defer Load(envDir)
+    calls := 0
+    validate := func(s string) error {
+        calls++
+        if s == "" || s == "bad" {
+            return errors.New("invalid")
+        }
+        return nil
+    }
+
+    os.Setenv("TEST_LAZY", "good")
+    defer os.Unsetenv("TEST_LAZY")
+    for i := 0; i < 3; i++ {
+        if got := GetEnvStringLazy("TEST_LAZY", validate, ""); got != "good" {
+            t.Errorf("got %q; want %q", got, "good")
+        }
+    }
+    if calls != 1 {
+        t.Errorf("validate ran %d times; want 1", calls)
+    }
+
+    // Later OS changes are not seen until the next reload
+    os.Setenv("TEST_LAZY", "bad")
+    if got := GetEnvStringLazy("TEST_LAZY", validate, ""); got != "good" {
+        t.Errorf("got %q; want kept %q", got, "good")
+    }
+
+    // After a reload the value is validated again and rejected
+    Reload()
+    defer func() {
+        if recover() == nil {
+            t.Errorf("expected panic on failed validation")
+        }
+        if calls != 2 {
+            t.Errorf("validate ran %d times; want 2", calls)
+        }
+    }()
+    GetEnvStringLazy("TEST_LAZY", validate, "")
+}
+
+// Benchmark for resolving a value with live lookups
+func BenchmarkGetEnvString(b *testing.B) {
+    SetEnvTransient("BENCH_CACHE", "value")
+    for i := 0; i < b.N; i++ {
+        GetEnvString("BENCH_CACHE", "")
+    }
+}
+
+// Benchmark for resolving a value from the cache
+func BenchmarkGetEnvStringCached(b *testing.B) {
+    SetEnvTransient("BENCH_CACHE", "value")
+    EnableCache(true)
+    defer EnableCache(false)
+    for i := 0; i < b.N; i++ {
+        GetEnvString("BENCH_CACHE", "")
+    }
+}
+
+// Benchmark for concurrent reads with the cache off, which must not contend
+// on any lock
+func BenchmarkGetEnvStringParallel(b *testing.B) {
+    SetEnvTransient("BENCH_CACHE", "value")
+    b.RunParallel(func(pb *testing.PB) {
+        for pb.Next() {
+            GetEnvString("BENCH_CACHE", "")
+        }
+    })
+}
+
+// Benchmark for concurrent reads served from the cache
+func BenchmarkGetEnvStringCachedParallel(b *testing.B) {
+    SetEnvTransient("BENCH_CACHE", "value")
+    EnableCache(true)
+    defer EnableCache(false)
+    b.RunParallel(func(pb *testing.PB) {
+        for pb.Next() {
+            GetEnvString("BENCH_CACHE", "")
+        }
+    })
+}
+
+// blockingLookup returns a lookup function whose first call for key waits
+// until release is closed, after signalling started, and returns the value
+// current at call time. set changes that value.
+func blockingLookup(key string) (lookup func(string) (string, bool), set func(string), started, release chan struct{}) {
+    var mu sync.Mutex
+    current := "old"
+    first := true
+    started, release = make(chan struct{}), make(chan struct{})
+    lookup = func(name string) (string, bool) {
+        if name != key {
+            return "", false
+        }
+        mu.Lock()
+        val, block := current, first
+        first = false
+        mu.Unlock()
+        if block {
+            close(started)
+            <-release
+        }
+        return val, true
+    }
+    set = func(val string) {
+        mu.Lock()
+        current = val
+        mu.Unlock()
+    }
+    return lookup, set, started, release
+}
+
+// Test that a value resolved before an invalidation is not cached after it
+func TestCacheStaleResolve(t *testing.T) {
+    defer Reset()
+    lookup, set, started, release := blockingLookup("TEST_CACHE_STALE")
+    SetLookupEnv(lookup)
+    EnableCache(true)
+
+    done := make(chan string)
+    go func() { done <- GetEnvString("TEST_CACHE_STALE", "") }()
+    <-started
+    set("new")
+    invalidateCache()
+    close(release)
+    if got := <-done; got != "old" {
+        t.Errorf("in-flight read got %q; want %q", got, "old")
+    }
+
+    // The in-flight result must not outlive the invalidation
+    if got := GetEnvString("TEST_CACHE_STALE", ""); got != "new" {
+        t.Errorf("got %q; want %q", got, "new")
+    }
+}
+
+// Test that GetEnvStringLazy does not keep a value resolved before an invalidation
+func TestGetEnvStringLazyStale(t *testing.T) {
+    defer Reset()
+    lookup, set, started, release := blockingLookup("TEST_LAZY_STALE")
+    SetLookupEnv(lookup)
+    accept := func(string) error { return nil }
+
+    done := make(chan string)
+    go func() { done <- GetEnvStringLazy("TEST_LAZY_STALE", accept, "") }()
+    <-started
+    set("new")
+    invalidateCache()
+    close(release)
+    <-done
+
+    if got := GetEnvStringLazy("TEST_LAZY_STALE", accept, ""); got != "new" {
+        t.Errorf("got %q; want %q", got, "new")
+    }
+}
@@ -0,0 +1,67 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Field describes a variable to coerce: its key, declared type and default
+// value in string form. Supported types are "string", "int", "float64",
+// "bool" and "duration".
+type Field struct {
+	Key     string
+	Type    string
+	Default string
+}
+
+// Coerce resolves every field and parses it to its declared type, falling
+// back to the field's default when the variable is not set. Fields that are
+// neither set nor given a default are optional and left out of the result.
+// Unlike the getters it does not report through the error handler: parse,
+// decryption and validation failures are collected and returned together,
+// alongside the values that could be parsed.
+func Coerce(fields []Field) (map[string]any, error) {
+	result := make(map[string]any, len(fields))
+	var errs []error
+	for _, field := range fields {
+		accessed.Store(field.Key, struct{}{})
+		val, _, err := resolve(field.Key)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if val == "" {
+			val = field.Default
+		}
+		if val == "" {
+			continue
+		}
+		parsed, err := coerceValue(field.Type, val)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("environment variable %s: %w", field.Key, err))
+			continue
+		}
+		result[field.Key] = parsed
+	}
+	return result, errors.Join(errs...)
+}
+
+// coerceValue parses val according to typ.
+func coerceValue(typ, val string) (any, error) {
+	switch typ {
+	case "string":
+		return val, nil
+	case "int":
+		return strconv.Atoi(val)
+	case "float64":
+		return strconv.ParseFloat(val, 64)
+	case "bool":
+		return parseBool(val)
+	case "duration":
+		return time.ParseDuration(val)
+	default:
+		return nil, fmt.Errorf("unsupported type %q", typ)
+	}
+}
@@ -0,0 +1,92 @@
+package env
+
+import (
+    "errors"
+    "os"
+    "strings"
+    "testing"
+    "time"
+)
+
+// Test for coercing several variables according to a schema
+func TestCoerce(t *testing.T) {
+    os.Setenv("TEST_COERCE_PORT", "8080")
+    os.Setenv("TEST_COERCE_DEBUG", "true")
+    defer os.Unsetenv("TEST_COERCE_PORT")
+    defer os.Unsetenv("TEST_COERCE_DEBUG")
+
+    got, err := Coerce([]Field{
+        {Key: "TEST_COERCE_PORT", Type: "int", Default: "80"},
+        {Key: "TEST_COERCE_DEBUG", Type: "bool", Default: "false"},
+        {Key: "TEST_COERCE_RATIO", Type: "float64", Default: "0.5"},
+        {Key: "TEST_COERCE_TIMEOUT", Type: "duration", Default: "3s"},
+        {Key: "TEST_COERCE_NAME", Type: "string", Default: "svc"},
+    })
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+
+    if got["TEST_COERCE_PORT"] != 8080 || got["TEST_COERCE_DEBUG"] != true {
+        t.Errorf("got %v; want parsed env values", got)
+    }
+    if got["TEST_COERCE_RATIO"] != 0.5 || got["TEST_COERCE_TIMEOUT"] != 3*time.Second || got["TEST_COERCE_NAME"] != "svc" {
+        t.Errorf("got %v; want parsed defaults", got)
+    }
+}
+
+// Test that coercion failures are aggregated
+func TestCoerceErrors(t *testing.T) {
+    os.Setenv("TEST_COERCE_BAD_INT", "eighty")
+    os.Setenv("TEST_COERCE_BAD_BOOL", "maybe")
+    defer os.Unsetenv("TEST_COERCE_BAD_INT")
+    defer os.Unsetenv("TEST_COERCE_BAD_BOOL")
+
+    got, err := Coerce([]Field{
+        {Key: "TEST_COERCE_BAD_INT", Type: "int"},
+        {Key: "TEST_COERCE_BAD_BOOL", Type: "bool"},
+        {Key: "TEST_COERCE_OK", Type: "string", Default: "fine"},
+    })
+    if err == nil {
+        t.Fatalf("expected an error")
+    }
+
+    // Both failures are reported, and valid fields are still returned
+    for _, key := range []string{"TEST_COERCE_BAD_INT", "TEST_COERCE_BAD_BOOL"} {
+        if !strings.Contains(err.Error(), key) {
+            t.Errorf("error %q does not mention %s", err, key)
+        }
+    }
+    if got["TEST_COERCE_OK"] != "fine" {
+        t.Errorf("got %v; want TEST_COERCE_OK to be coerced", got)
+    }
+}
+
+// Test that optional fields are omitted and resolution failures are collected
+func TestCoerceOptionalAndResolveErrors(t *testing.T) {
+    defer Reset()
+    RegisterValidator("TEST_COERCE_VALIDATED", func(string) error { return errors.New("rejected") })
+    os.Setenv("TEST_COERCE_VALIDATED", "x")
+    defer os.Unsetenv("TEST_COERCE_VALIDATED")
+
+    got, err := Coerce([]Field{
+        {Key: "TEST_COERCE_OPTIONAL_INT", Type: "int"},
+        {Key: "TEST_COERCE_OPTIONAL_BOOL", Type: "bool"},
+        {Key: "TEST_COERCE_OPTIONAL_DURATION", Type: "duration"},
+        {Key: "TEST_COERCE_VALIDATED", Type: "string"},
+    })
+
+    // Unset fields without a default are not errors and not in the result
+    for _, key := range []string{"TEST_COERCE_OPTIONAL_INT", "TEST_COERCE_OPTIONAL_BOOL", "TEST_COERCE_OPTIONAL_DURATION"} {
+        if _, ok := got[key]; ok {
+            t.Errorf("got %v; want %s omitted", got, key)
+        }
+        if err != nil && strings.Contains(err.Error(), key) {
+            t.Errorf("unexpected error for optional %s: %v", key, err)
+        }
+    }
+
+    // A validator failure is returned instead of panicking
+    if err == nil || !strings.Contains(err.Error(), "TEST_COERCE_VALIDATED") {
+        t.Errorf("got %v; want the validation failure", err)
+    }
+}
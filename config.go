@@ -0,0 +1,160 @@
+package env
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultDelimiter joins array values from structured config files into a
+// single delimited string, matching the split argument array getters expect.
+const DefaultDelimiter = ","
+
+// configSource is a structured config file registered for re-reading by Load.
+type configSource struct {
+	path string
+	load func(path string, dst map[string]string) error
+}
+
+// configSources lists the config files registered via LoadJSON and friends,
+// in registration order so later files override earlier ones.
+var configSources []configSource
+
+// LoadJSON reads a JSON object from path and merges its values into the
+// in-memory store. Nested objects are flattened into uppercase keys joined
+// with "_", so {"db":{"host":"x"}} becomes DB_HOST. Non-string scalars are
+// stringified and arrays are joined with DefaultDelimiter. The file is
+// re-read by Load and Reload. As with *.env files, the OS environment takes
+// precedence.
+func LoadJSON(path string) error {
+	return addConfigSource(path, loadJSONFile)
+}
+
+// LoadYAML reads a YAML mapping from path and merges its values into the
+// in-memory store, flattening nested keys the same way as LoadJSON. Scalars
+// are stringified and sequences are joined with DefaultDelimiter. The file is
+// re-read by Load and Reload.
+func LoadYAML(path string) error {
+	return addConfigSource(path, loadYAMLFile)
+}
+
+// LoadTOML reads a TOML document from path and merges its values into the
+// in-memory store, flattening tables into uppercase keys joined with "_".
+// Arrays are joined with DefaultDelimiter so the array getters can split
+// them. The file is re-read by Load and Reload.
+func LoadTOML(path string) error {
+	return addConfigSource(path, loadTOMLFile)
+}
+
+// addConfigSource loads path into the in-memory store and registers it so
+// subsequent calls to Load and Reload read it again.
+func addConfigSource(path string, load func(string, map[string]string) error) error {
+	values := make(map[string]string)
+	if err := load(path, values); err != nil {
+		return err
+	}
+	values = normalizeKeys(values)
+	envMu.Lock()
+	defer envMu.Unlock()
+	updateEnv(func(m map[string]string) {
+		mergeValues(m, values)
+
+		// Values set in memory keep their precedence over files
+		for key, val := range transientEnv {
+			m[key] = val
+		}
+		for key, val := range persistentEnv {
+			m[key] = val
+		}
+	})
+	loadedSources = append(loadedSources, envSource{name: path, values: values})
+	configSources = append(configSources, configSource{path: path, load: load})
+	invalidateCache()
+	return nil
+}
+
+// loadJSONFile parses the JSON object in path and flattens it into dst.
+func loadJSONFile(path string, dst map[string]string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var root map[string]any
+	if err := decoder.Decode(&root); err != nil {
+		return fmt.Errorf("invalid JSON config %s: %v", path, err)
+	}
+	flatten("", root, dst)
+	return nil
+}
+
+// loadYAMLFile parses the YAML mapping in path and flattens it into dst.
+func loadYAMLFile(path string, dst map[string]string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var root map[string]any
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return fmt.Errorf("invalid YAML config %s: %v", path, err)
+	}
+	flatten("", root, dst)
+	return nil
+}
+
+// loadTOMLFile parses the TOML document in path and flattens it into dst.
+func loadTOMLFile(path string, dst map[string]string) error {
+	var root map[string]any
+	if _, err := toml.DecodeFile(path, &root); err != nil {
+		return fmt.Errorf("invalid TOML config %s: %v", path, err)
+	}
+	flatten("", root, dst)
+	return nil
+}
+
+// flatten writes the scalar leaves of a decoded config tree into dst, joining
+// nested keys with "_" and uppercasing them.
+func flatten(prefix string, node any, dst map[string]string) {
+	switch v := node.(type) {
+	case map[string]any:
+		for key, child := range v {
+			flatten(joinKey(prefix, key), child, dst)
+		}
+	case map[any]any:
+		for key, child := range v {
+			flatten(joinKey(prefix, fmt.Sprint(key)), child, dst)
+		}
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			values = append(values, scalarString(item))
+		}
+		dst[prefix] = strings.Join(values, DefaultDelimiter)
+	default:
+		dst[prefix] = scalarString(v)
+	}
+}
+
+// joinKey appends key to prefix using the uppercase-underscore convention.
+func joinKey(prefix, key string) string {
+	key = strings.ToUpper(key)
+	if prefix == "" {
+		return key
+	}
+	return prefix + "_" + key
+}
+
+// scalarString formats a decoded scalar value; null becomes an empty string.
+func scalarString(v any) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
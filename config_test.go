@@ -0,0 +1,135 @@
+package env
+
+import (
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+// Test for loading flat and nested JSON config files
+func TestLoadJSON(t *testing.T) {
+    defer func() { configSources = nil }()
+    defer Load(envDir)
+
+    dir := t.TempDir()
+    file := filepath.Join(dir, "config.json")
+    os.WriteFile(file, []byte(`{
+        "TEST_JSON_PORT": "8080",
+        "test_json_db": {"host": "db.local", "port": 5432, "tls": {"enabled": true}},
+        "test_json_ratio": 0.25,
+        "test_json_hosts": ["a", "b"]
+    }`), 0o644)
+
+    if err := LoadJSON(file); err != nil {
+        t.Fatalf("LoadJSON failed: %v", err)
+    }
+
+    want := map[string]string{
+        "TEST_JSON_PORT":           "8080",
+        "TEST_JSON_DB_HOST":        "db.local",
+        "TEST_JSON_DB_PORT":        "5432",
+        "TEST_JSON_DB_TLS_ENABLED": "true",
+        "TEST_JSON_RATIO":          "0.25",
+        "TEST_JSON_HOSTS":          "a,b",
+    }
+    for k, v := range want {
+        if got := GetEnvString(k, ""); got != v {
+            t.Errorf("for key %q, got %q; want %q", k, got, v)
+        }
+    }
+
+    // Values are re-read on reload
+    os.WriteFile(file, []byte(`{"TEST_JSON_PORT": "9090"}`), 0o644)
+    Reload()
+    if got := GetEnvString("TEST_JSON_PORT", ""); got != "9090" {
+        t.Errorf("got %q after reload; want %q", got, "9090")
+    }
+
+    // Malformed files report an error
+    bad := filepath.Join(dir, "bad.json")
+    os.WriteFile(bad, []byte(`{"broken":`), 0o644)
+    if err := LoadJSON(bad); err == nil {
+        t.Errorf("expected error for malformed JSON")
+    }
+}
+
+// Test for loading nested YAML config files with sequences
+func TestLoadYAML(t *testing.T) {
+    defer func() { configSources = nil }()
+    defer Load(envDir)
+
+    file := filepath.Join(t.TempDir(), "config.yaml")
+    os.WriteFile(file, []byte(`
+test_yaml:
+  db:
+    host: db.local
+    port: 5432
+  debug: true
+  hosts:
+    - a.local
+    - b.local
+`), 0o644)
+
+    if err := LoadYAML(file); err != nil {
+        t.Fatalf("LoadYAML failed: %v", err)
+    }
+
+    want := map[string]string{
+        "TEST_YAML_DB_HOST": "db.local",
+        "TEST_YAML_DB_PORT": "5432",
+        "TEST_YAML_DEBUG":   "true",
+        "TEST_YAML_HOSTS":   "a.local,b.local",
+    }
+    for k, v := range want {
+        if got := GetEnvString(k, ""); got != v {
+            t.Errorf("for key %q, got %q; want %q", k, got, v)
+        }
+    }
+
+    // Sequences work with the array getters
+    got := GetEnvArrayString("TEST_YAML_HOSTS", DefaultDelimiter, nil)
+    if len(got) != 2 || got[0] != "a.local" || got[1] != "b.local" {
+        t.Errorf("got %v; want [a.local b.local]", got)
+    }
+}
+
+// Test for loading TOML config files with tables and arrays
+func TestLoadTOML(t *testing.T) {
+    defer func() { configSources = nil }()
+    defer Load(envDir)
+
+    file := filepath.Join(t.TempDir(), "config.toml")
+    os.WriteFile(file, []byte(`
+[test_toml]
+name = "service"
+
+[test_toml.db]
+host = "db.local"
+port = 5432
+
+[test_toml.workers]
+ports = [8080, 8081, 8082]
+`), 0o644)
+
+    if err := LoadTOML(file); err != nil {
+        t.Fatalf("LoadTOML failed: %v", err)
+    }
+
+    want := map[string]string{
+        "TEST_TOML_NAME":          "service",
+        "TEST_TOML_DB_HOST":       "db.local",
+        "TEST_TOML_DB_PORT":       "5432",
+        "TEST_TOML_WORKERS_PORTS": "8080,8081,8082",
+    }
+    for k, v := range want {
+        if got := GetEnvString(k, ""); got != v {
+            t.Errorf("for key %q, got %q; want %q", k, got, v)
+        }
+    }
+
+    // Arrays work with the array getters
+    got := GetEnvArrayInt("TEST_TOML_WORKERS_PORTS", DefaultDelimiter, nil)
+    if len(got) != 3 || got[0] != 8080 || got[2] != 8082 {
+        t.Errorf("got %v; want [8080 8081 8082]", got)
+    }
+}
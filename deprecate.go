@@ -0,0 +1,73 @@
+package env
+
+import (
+	"fmt"
+	"log"
+	"sync"
+)
+
+// deprecation records the replacement and removal note of a deprecated key.
+type deprecation struct {
+	newKey  string
+	removal string
+}
+
+var (
+	// deprecations maps deprecated keys registered via MarkDeprecatedUntil
+	// to their replacement.
+	deprecations = make(map[string]deprecation)
+
+	// warned holds the deprecated keys already warned about, so each
+	// warning is emitted once.
+	warned sync.Map
+
+	// warningHandler receives warnings when registered via
+	// SetWarningHandler.
+	warningHandler func(key, msg string)
+)
+
+// MarkDeprecatedUntil declares oldKey deprecated in favour of newKey, to be
+// removed in the given version or on the given date, e.g. "v2.0". Getters
+// for newKey fall back to oldKey while it is still set, and the first such
+// read emits a warning such as "Environment variable OLD is deprecated, use
+// NEW instead (removed in v2.0)". An empty removal leaves the note out.
+func MarkDeprecatedUntil(oldKey, newKey, removal string) {
+	deprecations[oldKey] = deprecation{newKey: newKey, removal: removal}
+	warned.Delete(oldKey)
+	AddAlias(newKey, oldKey)
+}
+
+// SetWarningHandler registers a handler for non-fatal warnings, such as reads
+// of deprecated keys, instead of writing them to the standard logger.
+// Passing nil restores the default.
+func SetWarningHandler(handler func(key, msg string)) {
+	warningHandler = handler
+}
+
+// warnDeprecated emits the deprecation warning for key once, if key was
+// marked deprecated.
+func warnDeprecated(key string) {
+	d, ok := deprecations[key]
+	if !ok {
+		return
+	}
+	if _, done := warned.LoadOrStore(key, struct{}{}); done {
+		return
+	}
+	msg := fmt.Sprintf("Environment variable %s is deprecated, use %s instead", key, d.newKey)
+	if d.removal != "" {
+		msg += fmt.Sprintf(" (removed in %s)", d.removal)
+	}
+	if warningHandler != nil {
+		warningHandler(key, msg)
+		return
+	}
+	log.Print(msg)
+}
+
+// resetDeprecations forgets all deprecations and the warning handler.
+func resetDeprecations() {
+	deprecations = make(map[string]deprecation)
+	warned.Clear()
+	warningHandler = nil
+}
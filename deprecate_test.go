@@ -0,0 +1,52 @@
+package env
+
+import (
+    "os"
+    "testing"
+)
+
+// Test that reading a deprecated key warns once with its removal note
+func TestMarkDeprecatedUntil(t *testing.T) {
+    defer Reset()
+    var warnings []string
+    SetWarningHandler(func(key, msg string) { warnings = append(warnings, msg) })
+    MarkDeprecatedUntil("TEST_OLD_DB_URL", "TEST_NEW_DB_URL", "v2.0")
+
+    os.Setenv("TEST_OLD_DB_URL", "postgres://old")
+    defer os.Unsetenv("TEST_OLD_DB_URL")
+    if got := GetEnvString("TEST_NEW_DB_URL", ""); got != "postgres://old" {
+        t.Errorf("got %q; want %q", got, "postgres://old")
+    }
+    GetEnvString("TEST_NEW_DB_URL", "")
+
+    want := "Environment variable TEST_OLD_DB_URL is deprecated, use TEST_NEW_DB_URL instead (removed in v2.0)"
+    if len(warnings) != 1 || warnings[0] != want {
+        t.Errorf("got %q; want one warning %q", warnings, want)
+    }
+
+    // Setting the new key takes precedence and emits no further warnings
+    os.Setenv("TEST_NEW_DB_URL", "postgres://new")
+    defer os.Unsetenv("TEST_NEW_DB_URL")
+    if got := GetEnvString("TEST_NEW_DB_URL", ""); got != "postgres://new" {
+        t.Errorf("got %q; want %q", got, "postgres://new")
+    }
+    if len(warnings) != 1 {
+        t.Errorf("got %d warnings; want 1", len(warnings))
+    }
+}
+
+// Test that an empty removal leaves the note out
+func TestMarkDeprecatedUntilNoRemoval(t *testing.T) {
+    defer Reset()
+    var warning string
+    SetWarningHandler(func(key, msg string) { warning = msg })
+    MarkDeprecatedUntil("TEST_OLD_PORT", "TEST_NEW_PORT", "")
+
+    os.Setenv("TEST_OLD_PORT", "80")
+    defer os.Unsetenv("TEST_OLD_PORT")
+    GetEnvString("TEST_NEW_PORT", "")
+
+    if want := "Environment variable TEST_OLD_PORT is deprecated, use TEST_NEW_PORT instead"; warning != want {
+        t.Errorf("got %q; want %q", warning, want)
+    }
+}
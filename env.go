@@ -3,123 +3,1878 @@ package env
 import (
 	"bufio"
 	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net/mail"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// envMap stores environment variables loaded from *.env files at runtime.
+// Variables from the OS environment (os.Getenv) take precedence over these.
+// The map behind the pointer is never modified once stored: writers build a
+// copy and swap it in, so readers get a consistent snapshot without locking.
+// Getters stay lock-free as long as the value cache is off; with EnableCache
+// they take a read lock on the cache.
+var envMap atomic.Pointer[map[string]string]
+
+// envMu serializes writers of envMap and the in-memory value stores.
+var envMu sync.Mutex
+
+// loadedEnv returns the current snapshot of variables loaded into memory.
+// The returned map must not be modified.
+func loadedEnv() map[string]string {
+	if m := envMap.Load(); m != nil {
+		return *m
+	}
+	return nil
+}
+
+// updateEnv copies the current snapshot, applies fn to the copy and swaps it
+// in. Callers must hold envMu.
+func updateEnv(fn func(m map[string]string)) {
+	current := loadedEnv()
+	next := make(map[string]string, len(current)+1)
+	for key, val := range current {
+		next[key] = val
+	}
+	fn(next)
+	envMap.Store(&next)
+}
+
+// profileEnvKey names the variable selecting which <name>.<profile>.env
+// files Load layers on top of the base *.env files.
+const profileEnvKey = "APP_ENV"
+
+// defaultProfiles are the profile names Load recognizes in file names until
+// SetKnownProfiles replaces them.
+var defaultProfiles = []string{"dev", "development", "test", "testing", "qa", "ci", "staging", "stage", "prod", "production"}
+
+// knownProfiles holds the profile names set via SetKnownProfiles.
+var knownProfiles = profileSet(defaultProfiles)
+
+// SetKnownProfiles sets the profile names Load recognizes in file names of
+// the form <name>.<profile>.env, replacing the defaults dev, development,
+// test, testing, qa, ci, staging, stage, prod and production. Files of a
+// known profile other than APP_ENV are skipped, so test settings never reach
+// production. Call Reset to restore the defaults.
+func SetKnownProfiles(names ...string) {
+	knownProfiles = profileSet(names)
+}
+
+// profileSet returns names as a set.
+func profileSet(names []string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+	return set
+}
+
+// defaultsFileKey names the variable pointing at a file whose values Load
+// applies at the lowest precedence.
+const defaultsFileKey = "DEFAULTS_FILE"
+
+// envDir is the directory *.env files were last loaded from by Load.
+var envDir string
+
+// persistentEnv stores values set via SetEnvPersistent so they can be
+// re-applied on top of freshly loaded files by Load and Reload.
+var persistentEnv = make(map[string]string)
+
+// transientEnv stores values set via SetEnvTransient until the next Load.
+var transientEnv = make(map[string]string)
+
+// envSource holds the values read from a single file, in load order.
+type envSource struct {
+	name   string
+	values map[string]string
+}
+
+// loadedSources lists the files read by the last Load, in precedence order.
+var loadedSources []envSource
+
+// lookupEnv resolves keys from the OS environment. It can be replaced with
+// SetLookupEnv, e.g. to isolate tests from the process environment.
+var lookupEnv = os.LookupEnv
+
+// init loads all environment variables from *.env files located in the same
+// directory as the compiled binary. These variables are stored in memory
+// (envMap) and are only used if the variable is not present in the system
+// environment (os.Getenv). Variables are never written into the system
+// environment to avoid exposure.
+func init() {
+	exePath, err := os.Executable()
+	if err != nil {
+		return
+	}
+	Load(filepath.Dir(exePath))
+}
+
+// Load replaces the variables loaded from *.env files with those found in dir
+// and remembers dir for subsequent calls to Reload. Files are applied in
+// natural name order, so numeric prefixes work as in conf.d directories and
+// the last file defining a key wins. Files named <name>.<profile>.env are
+// loaded on top of all others when APP_ENV equals their profile, and skipped
+// when it names another profile known to SetKnownProfiles; other dotted
+// names such as my.app.env load in name order like any other file.
+// If DEFAULTS_FILE names a file (relative paths are taken from dir), it is
+// loaded below all others to fill gaps, and only there if it is one of the
+// *.env files in dir. Config files registered via LoadJSON
+// are re-read on top, then values set via SetEnvPersistent are re-applied;
+// transient values are dropped. Finally, hooks registered via OnLoad run.
+// The returned LoadResult reports the files read and any lines skipped. See
+// SetStrictDuplicates for keys repeated within a single file. Keys with
+// bracketed indices, e.g. FOO[0]=a and FOO[1]=b, are collapsed into a single
+// delimited FOO=a,b that the array getters read; elements containing a comma
+// are quoted and need GetEnvArrayStringQuoted.
+func Load(dir string) (LoadResult, error) {
+	if osSnapshot.Load() != nil {
+		SnapshotOSEnv()
+	}
+
+	// Discover all *.env files in the directory
+	files, err := filepath.Glob(filepath.Join(dir, "*.env"))
+	if err != nil {
+		return LoadResult{}, err
+	}
+
+	// Apply files in natural order so later ones override earlier ones,
+	// e.g. 10-base.env before 20-override.env, and 9-a.env before 10-b.env
+	sort.SliceStable(files, func(i, j int) bool {
+		return naturalLess(filepath.Base(files[i]), filepath.Base(files[j]))
+	})
+
+	// Parse each file separately so Explain can report per-file values
+	var result LoadResult
+	var parsed []envSource
+	for _, file := range files {
+		values := make(map[string]string)
+		result.Skipped = append(result.Skipped, loadFile(file, values)...)
+		parsed = append(parsed, envSource{name: file, values: values})
+	}
+
+	// Layer the files of the profile selected by APP_ENV (app.staging.env)
+	// on top and skip those of other known profiles; any other dotted name,
+	// e.g. my.app.env, is a base file
+	active := sourceValue(profileEnvKey, parsed)
+	var sources, profileSources []envSource
+	for _, source := range parsed {
+		stem := strings.TrimSuffix(filepath.Base(source.name), ".env")
+		suffix := ""
+		if i := strings.LastIndex(stem, "."); i >= 0 {
+			suffix = stem[i+1:]
+		}
+		switch {
+		case suffix != "" && suffix == active:
+			profileSources = append(profileSources, source)
+		case suffix != "" && knownProfiles[suffix]:
+			continue
+		default:
+			sources = append(sources, source)
+		}
+	}
+	sources = append(sources, profileSources...)
+
+	// Fill gaps from the file named by DEFAULTS_FILE, below everything else
+	var loadErr error
+	if defaultsFile := sourceValue(defaultsFileKey, sources); defaultsFile != "" {
+		if !filepath.IsAbs(defaultsFile) {
+			defaultsFile = filepath.Join(dir, defaultsFile)
+		}
+		if _, err := os.Stat(defaultsFile); err != nil {
+			loadErr = err
+		} else if i := slices.IndexFunc(parsed, func(source envSource) bool {
+			return sameFile(source.name, defaultsFile)
+		}); i >= 0 {
+			// A defaults file among the *.env files moves to the bottom
+			// instead of also loading at its place in name order
+			sources = slices.DeleteFunc(sources, func(source envSource) bool {
+				return source.name == parsed[i].name
+			})
+			sources = append([]envSource{parsed[i]}, sources...)
+		} else {
+			values := make(map[string]string)
+			result.Skipped = append(result.Skipped, loadFile(defaultsFile, values)...)
+			sources = append([]envSource{{name: defaultsFile, values: values}}, sources...)
+		}
+	}
+
+	// Report keys repeated within a file when duplicates are strict
+	for _, skipped := range result.Skipped {
+		if skipped.Reason == duplicateKeyReason && loadErr == nil {
+			loadErr = skipped
+		}
+	}
+
+	envMu.Lock()
+
+	// Re-read config files registered via LoadJSON and friends
+	for _, source := range configSources {
+		values := make(map[string]string)
+		if err := source.load(source.path, values); err != nil {
+			if loadErr == nil {
+				loadErr = err
+			}
+			continue
+		}
+		sources = append(sources, envSource{name: source.path, values: normalizeKeys(values)})
+	}
+
+	loaded := make(map[string]string)
+	for _, source := range sources {
+		mergeValues(loaded, source.values)
+		result.Loaded += len(source.values)
+		result.Files = append(result.Files, source.name)
+	}
+	for key, val := range persistentEnv {
+		loaded[key] = val
+	}
+
+	envDir = dir
+	envMap.Store(&loaded)
+	loadedSources = sources
+	transientEnv = make(map[string]string)
+	invalidateCache()
+	envMu.Unlock()
+
+	for _, hook := range loadHooks {
+		hook()
+	}
+	notifySubscribers()
+	return result, loadErr
+}
+
+// sameFile reports whether paths a and b name the same file.
+func sameFile(a, b string) bool {
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	return errA == nil && errB == nil && os.SameFile(infoA, infoB)
+}
+
+// LoadResult reports what a call to Load or Reload did.
+type LoadResult struct {
+	// Loaded is the number of values read, summed over all files.
+	Loaded int
+	// Skipped lists the malformed lines that were ignored.
+	Skipped []ParseError
+	// Files lists the files read, from lowest to highest precedence.
+	Files []string
+}
+
+// ParseError describes a line of a .env file that could not be parsed.
+type ParseError struct {
+	File   string
+	Line   int
+	Text   string
+	Reason string
+}
+
+// Error returns the location and reason of the failure. The line itself is
+// left out since it may hold a secret.
+func (e ParseError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Reason)
+}
+
+// loadHooks holds the callbacks registered via OnLoad, in order.
+var loadHooks []func()
+
+// OnLoad registers fn to run at the end of every Load and Reload, after all
+// files are applied and before subscribers are notified, e.g. to derive
+// computed keys with SetEnvTransient. Values set this way are dropped by the
+// next Load and recomputed when fn runs again. Hooks run in registration
+// order.
+func OnLoad(fn func()) {
+	loadHooks = append(loadHooks, fn)
+}
+
+// sourceValue returns the value of key from the OS environment or, if unset
+// there, from the last of sources defining it.
+func sourceValue(key string, sources []envSource) string {
+	key = normalized(key)
+	if val, ok := lookupOS(key); ok {
+		return val
+	}
+	var val string
+	for _, source := range sources {
+		if v, exists := source.values[key]; exists {
+			val = v
+		}
+	}
+	return val
+}
+
+// Reload re-reads the *.env files from the directory last passed to Load,
+// picking up changes made since. Persistent values survive the reload.
+// The loaded values are swapped in at once, so reads running concurrently
+// see either the old or the new set, never a mix of both.
+func Reload() (LoadResult, error) {
+	return Load(envDir)
+}
+
+// loadFile parses a single .env file line-by-line into dst and returns the
+// malformed lines it skipped. Unreadable files are skipped entirely.
+func loadFile(file string, dst map[string]string) []ParseError {
+	defer collapseIndexed(dst)
+	if parseWorkers > 1 {
+		return loadFileParallel(file, dst, parseWorkers)
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var skipped []ParseError
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		key, val, ok, reason := parseLine(scanner.Text())
+		if reason != "" {
+			skipped = append(skipped, ParseError{File: file, Line: line, Text: scanner.Text(), Reason: reason})
+		} else if ok {
+			if reason := storeValue(dst, key, val); reason != "" {
+				skipped = append(skipped, ParseError{File: file, Line: line, Text: scanner.Text(), Reason: reason})
+			}
+		}
+	}
+	return skipped
+}
+
+// collapseIndexed replaces keys with bracketed indices, e.g. FOO[0] and
+// FOO[1], with a single FOO holding their values in index order, joined by
+// DefaultDelimiter. Elements containing the delimiter are double-quoted so
+// GetEnvArrayStringQuoted can split them back; all others, apostrophes and
+// quotes included, are kept as is for the plain array getters. Gaps in the
+// indices are skipped, and the collapsed value replaces any plain FOO in the
+// same file.
+func collapseIndexed(values map[string]string) {
+	type element struct {
+		index int
+		val   string
+	}
+	arrays := make(map[string][]element)
+	for key, val := range values {
+		name, rest, ok := strings.Cut(key, "[")
+		if !ok || name == "" || !strings.HasSuffix(rest, "]") {
+			continue
+		}
+		index, err := strconv.Atoi(strings.TrimSuffix(rest, "]"))
+		if err != nil || index < 0 {
+			continue
+		}
+		arrays[name] = append(arrays[name], element{index, val})
+		delete(values, key)
+	}
+	for name, elems := range arrays {
+		sort.Slice(elems, func(i, j int) bool { return elems[i].index < elems[j].index })
+		vals := make([]string, len(elems))
+		for i, elem := range elems {
+			vals[i] = elem.val
+			if strings.Contains(elem.val, DefaultDelimiter) {
+				vals[i] = `"` + strings.ReplaceAll(elem.val, `"`, `"'"'"`) + `"`
+			}
+		}
+		values[name] = strings.Join(vals, DefaultDelimiter)
+	}
+}
+
+// strictDuplicates makes a key repeated within one .env file an error
+// instead of letting its last definition win.
+var strictDuplicates bool
+
+// duplicateKeyReason is the ParseError reason of a repeated key in strict
+// mode.
+const duplicateKeyReason = "duplicate key"
+
+// SetStrictDuplicates controls how Load treats a key defined more than once
+// in the same .env file. By default (lenient) the last definition wins, just
+// as a later file overrides an earlier one. In strict mode the first
+// definition is kept, each repeat is reported in LoadResult.Skipped and Load
+// returns the first of them as an error. Keys repeated across different files
+// are never affected.
+func SetStrictDuplicates(strict bool) {
+	strictDuplicates = strict
+}
+
+// storeValue sets dst[key] to val. In strict mode a key already in dst is
+// left alone and a reason is returned instead.
+func storeValue(dst map[string]string, key, val string) (reason string) {
+	key = normalized(key)
+	if _, exists := dst[key]; exists && strictDuplicates {
+		return duplicateKeyReason
+	}
+	dst[key] = val
+	return ""
+}
+
+// parseLine parses a single key=value line of a .env file. Empty lines and
+// comments are reported as not ok; malformed lines also come with a reason.
+func parseLine(line string) (key, val string, ok bool, reason string) {
+	// Ignore empty lines and comments
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return "", "", false, ""
+	}
+
+	// Parse key=value pairs
+	kv := strings.SplitN(line, "=", 2)
+	if len(kv) != 2 {
+		return "", "", false, "missing '='"
+	}
+	key = strings.TrimSpace(kv[0])
+	if key == "" {
+		return "", "", false, "missing key"
+	}
+	if !trimValues {
+		return key, kv[1], true, ""
+	}
+	return key, strings.TrimSpace(kv[1]), true, ""
+}
+
+// trimValues makes the .env parser strip surrounding whitespace from values.
+var trimValues = true
+
+// SetTrimValues controls whether Load strips leading and trailing whitespace
+// from .env values, which it does by default. Pass false to keep values
+// exactly as written after the '=', e.g. a separator that is a single space.
+// Keys are always trimmed.
+func SetTrimValues(trim bool) {
+	trimValues = trim
+}
+
+// naturalLess compares a and b treating runs of digits as numbers, so that
+// "9-a" sorts before "10-b". Other characters compare byte-wise.
+func naturalLess(a, b string) bool {
+	for a != "" && b != "" {
+		if isDigit(a[0]) && isDigit(b[0]) {
+			i, j := 0, 0
+			for i < len(a) && isDigit(a[i]) {
+				i++
+			}
+			for j < len(b) && isDigit(b[j]) {
+				j++
+			}
+			na := strings.TrimLeft(a[:i], "0")
+			nb := strings.TrimLeft(b[:j], "0")
+			if len(na) != len(nb) {
+				return len(na) < len(nb)
+			}
+			if na != nb {
+				return na < nb
+			}
+			a, b = a[i:], b[j:]
+			continue
+		}
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) < len(b)
+}
+
+// mergeValues copies values into dst, skipping keys that are already present
+// in the system environment.
+func mergeValues(dst, values map[string]string) {
+	for key, val := range values {
+		// Only load the value if it's not already in the system environment
+		if _, exists := lookupOS(key); !exists {
+			dst[key] = val
+		}
+	}
+}
+
+// SetEnvPersistent sets a value in the in-memory store that survives Load and
+// Reload. As with file values, the OS environment still takes precedence.
+// The process environment is never modified.
+func SetEnvPersistent(key, value string) {
+	key = normalized(key)
+	envMu.Lock()
+	defer envMu.Unlock()
+	persistentEnv[key] = value
+	updateEnv(func(m map[string]string) { m[key] = value })
+	invalidateCache()
+}
+
+// SetEnvTransient sets a value in the in-memory store until the next Load or
+// Reload replaces it. The process environment is never modified.
+func SetEnvTransient(key, value string) {
+	key = normalized(key)
+	envMu.Lock()
+	defer envMu.Unlock()
+	transientEnv[key] = value
+	updateEnv(func(m map[string]string) { m[key] = value })
+	invalidateCache()
+}
+
+// overrideEnv stores values applied via ApplyOverrides, which take
+// precedence over every other source. Like envMap, the map is replaced
+// wholesale rather than modified.
+var overrideEnv atomic.Pointer[map[string]string]
+
+// ApplyOverrides sets KEY=VALUE pairs, e.g. from repeated --set command-line
+// flags, at the highest precedence: above the OS environment and all loaded
+// files. Overrides survive Load and Reload. If any pair is malformed, an
+// error is returned and none are applied.
+func ApplyOverrides(pairs []string) error {
+	parsed := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		key, val, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return fmt.Errorf("invalid override %q: expected KEY=VALUE", pair)
+		}
+		parsed[normalized(key)] = val
+	}
+
+	envMu.Lock()
+	defer envMu.Unlock()
+	next := make(map[string]string)
+	if current := overrideEnv.Load(); current != nil {
+		for key, val := range *current {
+			next[key] = val
+		}
+	}
+	for key, val := range parsed {
+		next[key] = val
+	}
+	overrideEnv.Store(&next)
+	invalidateCache()
+	return nil
+}
+
+// overrides returns the values applied via ApplyOverrides.
+// The returned map must not be modified.
+func overrides() map[string]string {
+	if m := overrideEnv.Load(); m != nil {
+		return *m
+	}
+	return nil
+}
+
+// SetLookupEnv replaces the function used to resolve keys from the OS
+// environment, which defaults to os.LookupEnv. Functions that enumerate all
+// variables (GetEnvMatching, GetEnvStringFold, UnusedKeys and the slice and
+// map binding of Unmarshal) use the enumerator set via SetEnviron instead, so
+// set both to replace the OS environment completely. Call Reset to restore
+// the default.
+func SetLookupEnv(fn func(string) (string, bool)) {
+	lookupEnv = fn
+	osSnapshot.Store(nil)
+	invalidateCache()
+}
+
+// environOS enumerates the OS environment as "key=value" pairs, in the form
+// returned by os.Environ.
+var environOS = os.Environ
+
+// SetEnviron replaces the function used to enumerate the OS environment,
+// which defaults to os.Environ. It is the counterpart of SetLookupEnv for
+// functions that list all variables, such as GetEnvMatching, and for
+// SnapshotOSEnv. fn must return "key=value" pairs. Call Reset to restore the
+// default.
+func SetEnviron(fn func() []string) {
+	environOS = fn
+}
+
+// parseEnviron turns "key=value" pairs into a map, skipping malformed ones.
+func parseEnviron(pairs []string) map[string]string {
+	values := make(map[string]string, len(pairs))
+	for _, kv := range pairs {
+		if key, val, ok := strings.Cut(kv, "="); ok && key != "" {
+			values[key] = val
+		}
+	}
+	return values
+}
+
+// osSnapshot holds a copy of the OS environment taken by SnapshotOSEnv, or
+// nil when reads go to lookupEnv. Like envMap, it is swapped atomically so
+// Load can refresh it while getters read it.
+var osSnapshot atomic.Pointer[map[string]string]
+
+// lookupOS resolves key from the OS environment snapshot, if one was taken,
+// or else through lookupEnv.
+func lookupOS(key string) (string, bool) {
+	if snapshot := osSnapshot.Load(); snapshot != nil {
+		val, ok := (*snapshot)[key]
+		return val, ok
+	}
+	return lookupEnv(key)
+}
+
+// SnapshotOSEnv copies the current OS environment into memory and serves all
+// OS-level reads from that copy instead of calling os.LookupEnv each time,
+// which is cheaper in hot paths. The snapshot is refreshed by Load and
+// Reload. The tradeoff is that later os.Setenv and os.Unsetenv calls are not
+// seen until the next refresh. Call Reset to return to live lookups.
+func SnapshotOSEnv() {
+	snapshot := parseEnviron(environOS())
+	osSnapshot.Store(&snapshot)
+	invalidateCache()
+}
+
+// Reset restores all package settings to their defaults: the OS lookup and
+// enumeration functions (ending any snapshot), the error handler, registered decryptors,
+// validators and transforms, load hooks, the key prefix, aliases and
+// normalizer, the active profile, the base directory, the jitter source, the
+// value cache, the number of parse workers, the map delimiters, deprecations,
+// the warning handler, custom boolean words, strict duplicate keys, value
+// trimming, values read by GetEnvStringOrStdin and the known profiles.
+// Loaded values are left untouched.
+func Reset() {
+	lookupEnv = os.LookupEnv
+	environOS = os.Environ
+	osSnapshot.Store(nil)
+	errorHandler = nil
+	decryptors = nil
+	validators = make(map[string]func(string) error)
+	transforms = make(map[string]func(string) string)
+	loadHooks = nil
+	keyPrefix = ""
+	aliases = make(map[string][]string)
+	normalizeKey = nil
+	profile = ""
+	baseDir = ""
+	jitterMu.Lock()
+	jitterRand = nil
+	jitterMu.Unlock()
+	EnableCache(false)
+	parseWorkers = 0
+	mapEntryDelimiter = DefaultDelimiter
+	mapKVDelimiter = ":"
+	resetDeprecations()
+	trueValues, falseValues = nil, nil
+	strictDuplicates = false
+	trimValues = true
+	stdinValues.Clear()
+	knownProfiles = profileSet(defaultProfiles)
+}
+
+// GetEnvString retrieves an environment variable's value as a string.
+// It first checks the OS environment, then loaded *.env files, and finally falls back to the default.
+func GetEnvString(key, defaultValue string) string {
+	if val, ok := lookup(key); ok {
+		return val
+	}
+	return defaultValue
+}
+
+// LookupEnvString retrieves an environment variable's value as a string and
+// reports whether it is set. Unlike GetEnvString it distinguishes an absent
+// key (ok=false) from one explicitly set to an empty string (ok=true).
+func LookupEnvString(key string) (value string, ok bool) {
+	return lookup(key)
+}
+
+// RawValue returns the value of key exactly as stored in memory from loaded
+// files, config files or in-memory sets, for diagnostics. It ignores the OS
+// environment and overrides, and applies no decryption, transform or
+// validation.
+func RawValue(key string) (string, bool) {
+	val, ok := loadedEnv()[key]
+	return val, ok
+}
+
+// lookup resolves a key from overrides, then the OS environment, then loaded
+// *.env files, decrypting values that carry a registered decryptor prefix.
+// The boolean reports whether the key was found in any source.
+func lookup(key string) (string, bool) {
+	// Load before Store, since storing takes a lock even for a known key
+	if _, seen := accessed.Load(key); !seen {
+		accessed.Store(key, struct{}{})
+	}
+	val, ok, err := resolve(key)
+	if err != nil {
+		parseFailed(key, err)
+	}
+	return val, ok
+}
+
+// resolve does the work of lookup, returning decryption and validation
+// failures instead of reporting them. A key whose value cannot be decrypted
+// or fails its validator is treated as unset.
+func resolve(key string) (string, bool, error) {
+	entry, hit, gen := cacheGet(key)
+	if hit {
+		return entry.val, entry.ok, nil
+	}
+	val, ok := findPrefixed(key)
+	for _, alias := range aliases[key] {
+		if ok {
+			break
+		}
+		if val, ok = findPrefixed(alias); ok {
+			warnDeprecated(alias)
+		}
+	}
+	if !ok {
+		cachePut(key, cacheEntry{}, gen)
+		return "", false, nil
+	}
+	plaintext, err := decrypt(val)
+	if err != nil {
+		return "", false, fmt.Errorf("Environment variable %s could not be decrypted: %v", key, err)
+	}
+	if transform, ok := transforms[key]; ok {
+		plaintext = transform(plaintext)
+	}
+	if validate, ok := validators[key]; ok {
+		if err := validate(plaintext); err != nil {
+			return "", false, fmt.Errorf("Environment variable %s failed validation: %v", key, err)
+		}
+	}
+	cachePut(key, cacheEntry{val: plaintext, ok: true}, gen)
+	return plaintext, true, nil
+}
+
+// find looks name up in overrides, then the OS environment, then values
+// loaded into memory, returning the stored value as is.
+func find(name string) (string, bool) {
+	name = normalized(name)
+	val, ok := overrides()[name]
+	if !ok {
+		val, ok = lookupOS(name)
+	}
+	if !ok {
+		val, ok = loadedEnv()[name]
+	}
+	if ok {
+		if _, seen := found.Load(name); !seen {
+			found.Store(name, struct{}{})
+		}
+	}
+	return val, ok
+}
+
+// normalizeKey is the key canonicalizer set via SetNormalizeKeys, or nil.
+var normalizeKey func(string) string
+
+// SetNormalizeKeys sets fn to canonicalize every key, e.g. strings.ToUpper
+// when a provider emits lowercase keys but code reads uppercase ones. It is
+// applied to keys read from files, config files, in-memory sets and
+// overrides, and to the keys getters look up, so both sides meet. Keys
+// already loaded are only normalized by the next Load or Reload. Pass nil,
+// or call Reset, to turn normalization off.
+func SetNormalizeKeys(fn func(string) string) {
+	normalizeKey = fn
+	invalidateCache()
+}
+
+// normalized returns key canonicalized by the function set via
+// SetNormalizeKeys.
+func normalized(key string) string {
+	if normalizeKey == nil {
+		return key
+	}
+	return normalizeKey(key)
+}
+
+// normalizeKeys returns values with every key canonicalized by the function
+// set via SetNormalizeKeys.
+func normalizeKeys(values map[string]string) map[string]string {
+	if normalizeKey == nil {
+		return values
+	}
+	result := make(map[string]string, len(values))
+	for key, val := range values {
+		result[normalizeKey(key)] = val
+	}
+	return result
+}
+
+// findPrefixed looks name up under the prefix set via SetPrefix, falling
+// back to name as given.
+func findPrefixed(name string) (string, bool) {
+	val, ok := find(keyPrefix + name)
+	if !ok && keyPrefix != "" {
+		val, ok = find(name)
+	}
+	return val, ok
+}
+
+// aliases maps keys to the alternative names registered via AddAlias.
+var aliases = make(map[string][]string)
+
+// AddAlias makes getters for key fall back to alias when key itself is not
+// set, e.g. AddAlias("DB_URL", "DATABASE_URL") lets call sites keep reading
+// DB_URL while deployments migrate to DATABASE_URL. A key set under its own
+// name always takes precedence. Aliases added for the same key are tried in
+// the order they were added.
+func AddAlias(key, alias string) {
+	aliases[key] = append(aliases[key], alias)
+	invalidateCache()
+}
+
+// keyPrefix is the namespace set via SetPrefix.
+var keyPrefix string
+
+// SetPrefix namespaces all lookups under prefix, e.g. with "MYAPP_" getters
+// can be called with the short name "PORT" to resolve MYAPP_PORT from the OS
+// environment and loaded files alike. Keys without the prefixed variable
+// fall back to the name as given, so full names and shared variables such
+// as HOME keep working. Call Reset to remove the prefix.
+func SetPrefix(prefix string) {
+	keyPrefix = prefix
+	invalidateCache()
+}
+
+// decryptor pairs a value prefix with the function that decrypts it.
+type decryptor struct {
+	prefix string
+	fn     func(string) (string, error)
+}
+
+// decryptors holds the decryptors registered via RegisterDecryptor.
+var decryptors []decryptor
+
+// RegisterDecryptor registers fn to decrypt values starting with prefix, e.g.
+// "enc:". Matching values are passed to fn with the prefix removed whenever
+// they are resolved. Registering the same prefix again replaces its decryptor.
+func RegisterDecryptor(prefix string, fn func(string) (string, error)) {
+	for i := range decryptors {
+		if decryptors[i].prefix == prefix {
+			decryptors[i].fn = fn
+			invalidateCache()
+			return
+		}
+	}
+	decryptors = append(decryptors, decryptor{prefix: prefix, fn: fn})
+	invalidateCache()
+}
+
+// decrypt applies the first decryptor whose prefix matches val. Values
+// without a registered prefix are returned unchanged.
+func decrypt(val string) (string, error) {
+	for _, d := range decryptors {
+		if ciphertext, ok := strings.CutPrefix(val, d.prefix); ok {
+			return d.fn(ciphertext)
+		}
+	}
+	return val, nil
+}
+
+// validators holds the validators registered via RegisterValidator.
+var validators = make(map[string]func(string) error)
+
+// RegisterValidator registers fn to check every resolved value of key, so
+// rules live in one place instead of at each call site. A value rejected by
+// fn is reported through the error handler (panicking by default) and the
+// getter falls back to its default. Registering a key again replaces its
+// validator.
+func RegisterValidator(key string, fn func(string) error) {
+	validators[key] = fn
+	invalidateCache()
+}
+
+// transforms holds the canonicalizers registered via RegisterTransform.
+var transforms = make(map[string]func(string) string)
+
+// RegisterTransform registers fn to canonicalize every resolved value of key,
+// e.g. strings.ToUpper for REGION, whether it comes from the OS environment,
+// a loaded file or memory. It runs after decryption and before any validator
+// registered for key. Registering a key again replaces its transform.
+func RegisterTransform(key string, fn func(string) string) {
+	transforms[key] = fn
+	invalidateCache()
+}
+
+// GetEnvReader returns a reader over an environment variable's value, or over
+// the default if it is not set, for APIs that consume an io.Reader such as
+// decoders.
+func GetEnvReader(key string, defaultValue string) io.Reader {
+	return strings.NewReader(GetEnvString(key, defaultValue))
+}
+
+// stdin and promptOutput are where GetEnvStringOrStdin reads values and
+// writes prompts. Tests replace them.
+var (
+	stdin        io.Reader = os.Stdin
+	promptOutput io.Writer = os.Stderr
+)
+
+// stdinValues holds the values read by GetEnvStringOrStdin, keyed by key.
+var stdinValues sync.Map
+
+// GetEnvStringOrStdin retrieves an environment variable's value like
+// GetEnvString, except that a value of "-" means the value is entered
+// interactively: prompt is written to stderr and a line is read from stdin.
+// When stdin is a terminal the input is not echoed, so secrets stay off the
+// screen. This happens once per key; later calls return the same line until
+// Reset. If reading fails the error is reported through the error handler and
+// defaultValue returned.
+func GetEnvStringOrStdin(key, prompt, defaultValue string) string {
+	val := GetEnvString(key, defaultValue)
+	if val != "-" {
+		return val
+	}
+	if cached, ok := stdinValues.Load(key); ok {
+		return cached.(string)
+	}
+
+	fmt.Fprint(promptOutput, prompt)
+	var line string
+	var err error
+	if f, ok := stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		var secret []byte
+		secret, err = term.ReadPassword(int(f.Fd()))
+		line = string(secret)
+
+		// The newline typed by the user is not echoed either
+		fmt.Fprintln(promptOutput)
+	} else {
+		line, err = readLine(stdin)
+	}
+	if err != nil {
+		parseFailed(key, fmt.Errorf("Environment variable %s could not be read from stdin: %v", key, err))
+		return defaultValue
+	}
+	actual, _ := stdinValues.LoadOrStore(key, line)
+	return actual.(string)
+}
+
+// readLine reads r one byte at a time up to the next newline, so no input
+// meant for later reads is consumed. The newline and a preceding carriage
+// return are dropped; a final line without newline is accepted.
+func readLine(r io.Reader) (string, error) {
+	var line []byte
+	buf := make([]byte, 1)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if buf[0] == '\n' {
+				break
+			}
+			line = append(line, buf[0])
+		}
+		if err == io.EOF && len(line) > 0 {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimSuffix(string(line), "\r"), nil
+}
+
+// GetEnvInferred retrieves an environment variable's value parsed into the
+// most specific type it fits, trying in order: int, float64, bool,
+// time.Duration and finally string. So "42" yields int 42, "1.5" float64
+// 1.5, "true" bool true, "5s" a time.Duration and anything else the string
+// itself. Note that "1" and "0" are ints, not booleans. Only finite decimal
+// numbers are floats, so "nan", "Infinity", "0x1p4" and "1_000" stay
+// strings. Returns nil if the variable is not set.
+func GetEnvInferred(key string) any {
+	val, ok := lookup(key)
+	if !ok {
+		return nil
+	}
+	if i, err := strconv.Atoi(val); err == nil {
+		return i
+	}
+	// Words such as "nan" and "Infinity", hex forms such as "0x1p4" and
+	// underscore separators such as "1_000" are accepted by ParseFloat but
+	// read as strings here, as Atoi rejects them too
+	if f, err := strconv.ParseFloat(val, 64); err == nil && !math.IsNaN(f) && !math.IsInf(f, 0) &&
+		!strings.ContainsAny(val, "xX_") {
+		return f
+	}
+	if b, err := parseBool(val); err == nil {
+		return b
+	}
+	if d, err := time.ParseDuration(val); err == nil {
+		return d
+	}
+	return val
+}
+
+// GetEnvStringTransform retrieves an environment variable's value as a string
+// and passes it through transform, e.g. for trimming or lowercasing.
+// The transform is only applied to resolved values, never to the default.
+func GetEnvStringTransform(key, defaultValue string, transform func(string) string) string {
+	if val, ok := lookup(key); ok {
+		return transform(val)
+	}
+	return defaultValue
+}
+
+// GetEnvStringValid retrieves an environment variable's value as a string and
+// checks it with valid. The default value is returned without validation.
+// Panics with the validator's error if the value exists but is rejected.
+func GetEnvStringValid(key, defaultValue string, valid func(string) error) string {
+	if val, ok := lookup(key); ok {
+		if err := valid(val); err != nil {
+			parseFailed(key, fmt.Errorf("Environment variable %s is not valid: %v", key, err))
+			return defaultValue
+		}
+		return val
+	}
+	return defaultValue
+}
+
+// GetEnvStringFold retrieves an environment variable's value as a string,
+// trying the exact key first and then a case-insensitive match against all
+// known keys. The fallback scan is O(n) in the number of variables; if several
+// keys match, the lexically smallest one wins.
+func GetEnvStringFold(key, defaultValue string) string {
+	if val, ok := lookup(key); ok {
+		return val
+	}
+	match := ""
+	for name := range environ() {
+		if strings.EqualFold(name, key) && (match == "" || name < match) {
+			match = name
+		}
+	}
+	if match != "" {
+		if val, ok := lookup(match); ok {
+			return val
+		}
+	}
+	return defaultValue
+}
+
+// GetEnvStringURLDecoded retrieves an environment variable's value and
+// decodes percent-encoding as in URL query strings, e.g. NAME=John%20Doe
+// yields "John Doe". A "+" decodes to a space. Panics on malformed encoding.
+func GetEnvStringURLDecoded(key, defaultValue string) string {
+	if val := GetEnvString(key, ""); val != "" {
+		decoded, err := url.QueryUnescape(val)
+		if err != nil {
+			parseFailed(key, fmt.Errorf("Environment variable %s is not valid percent-encoding: %v", key, err))
+			return defaultValue
+		}
+		return decoded
+	}
+	return defaultValue
+}
+
+// baseDir is the directory set via SetBaseDir that GetEnvPathAbs resolves
+// relative paths against. When empty, the directory last passed to Load is
+// used.
+var baseDir string
+
+// SetBaseDir sets the directory that GetEnvPathAbs resolves relative paths
+// against, e.g. the directory of the config file. Call Reset to go back to
+// the directory last passed to Load.
+func SetBaseDir(dir string) {
+	baseDir = dir
+}
+
+// GetEnvPathAbs retrieves an environment variable's value as a file path,
+// joining relative paths onto the base directory set via SetBaseDir (or the
+// directory last passed to Load) and cleaning the result. Absolute paths are
+// only cleaned. The default is returned as is.
+func GetEnvPathAbs(key, defaultValue string) string {
+	if val := GetEnvString(key, ""); val != "" {
+		if filepath.IsAbs(val) {
+			return filepath.Clean(val)
+		}
+		base := baseDir
+		if base == "" {
+			base = envDir
+		}
+		return filepath.Join(base, val)
+	}
+	return defaultValue
+}
+
+// pathListSeparator separates the entries read by GetEnvPathList. Tests
+// replace it to exercise other platforms.
+var pathListSeparator = string(os.PathListSeparator)
+
+// GetEnvPathList retrieves a PATH-like environment variable's value as a list
+// of paths, split on the OS path list separator (':' on Unix, ';' on
+// Windows). Empty entries are dropped. Returns the default if the variable is
+// not set or holds no entries.
+func GetEnvPathList(key string, defaultValue []string) []string {
+	if val := GetEnvString(key, ""); val != "" {
+		var paths []string
+		for _, path := range strings.Split(val, pathListSeparator) {
+			if path != "" {
+				paths = append(paths, path)
+			}
+		}
+		if len(paths) > 0 {
+			return paths
+		}
+	}
+	return defaultValue
+}
+
+// GetEnvStringRequiredIf retrieves an environment variable's value as a string
+// that is required only when condKey is set to condValue, e.g. S3_BUCKET when
+// STORAGE=s3. Returns an error if the condition holds but key is not set;
+// otherwise returns the value or the default.
+func GetEnvStringRequiredIf(key, condKey, condValue, defaultValue string) (string, error) {
+	if val, ok := lookup(key); ok {
+		return val, nil
+	}
+	if cond, ok := lookup(condKey); ok && cond == condValue {
+		return "", fmt.Errorf("environment variable %s is required when %s=%s", key, condKey, condValue)
+	}
+	return defaultValue, nil
+}
+
+// profile is the active profile set via SetProfile.
+var profile string
+
+// SetProfile sets the active profile used by GetEnvStringProfiled, e.g. "dev"
+// or "prod". An empty name disables profile lookups.
+func SetProfile(name string) {
+	profile = name
+}
+
+// GetEnvStringProfiled retrieves an environment variable's value as a string,
+// preferring a profile-specific key such as PORT.prod over PORT when a
+// profile is active.
+func GetEnvStringProfiled(key, defaultValue string) string {
+	if profile != "" {
+		if val, ok := lookup(key + "." + profile); ok {
+			return val
+		}
+	}
+	return GetEnvString(key, defaultValue)
+}
+
+// GetEnvIndirect resolves pointerKey to the name of another variable and
+// returns that variable's value, e.g. ACTIVE_PROFILE_VAR=PROFILE_PROD reads
+// PROFILE_PROD. Returns the default if pointerKey is not set or names a
+// variable that is not set.
+func GetEnvIndirect(pointerKey, defaultValue string) string {
+	target := GetEnvString(pointerKey, "")
+	if target == "" {
+		return defaultValue
+	}
+	return GetEnvString(target, defaultValue)
+}
+
+// GetEnvStringOrKey retrieves key, falling back to the value of fallbackKey
+// and then to the literal default, e.g. SECONDARY_URL defaulting to
+// PRIMARY_URL.
+func GetEnvStringOrKey(key, fallbackKey, defaultValue string) string {
+	if val, ok := lookup(key); ok {
+		return val
+	}
+	return GetEnvString(fallbackKey, defaultValue)
+}
+
+// GetEnvStringJoin resolves each of keys in order and joins the values that
+// are set with sep, skipping absent keys. Returns the default if none are set.
+func GetEnvStringJoin(keys []string, sep, defaultValue string) string {
+	values := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if val, ok := lookup(key); ok {
+			values = append(values, val)
+		}
+	}
+	if len(values) == 0 {
+		return defaultValue
+	}
+	return strings.Join(values, sep)
+}
+
+// GetEnvStringExpandFunc retrieves an environment variable's value as a string
+// and expands ${VAR} and $VAR references in it using mapping, which can
+// resolve names from any source such as a map or a database. The default
+// value is expanded too. Unresolved references become whatever mapping
+// returns for them, typically an empty string.
+func GetEnvStringExpandFunc(key, defaultValue string, mapping func(string) string) string {
+	return os.Expand(GetEnvString(key, defaultValue), mapping)
+}
+
+// GetEnvStringMasked retrieves an environment variable's value masked with '*'
+// for display, revealing only the last reveal characters. The default value is
+// returned as-is if the variable is not set.
+func GetEnvStringMasked(key string, reveal int, defaultValue string) string {
+	if val, ok := lookup(key); ok {
+		return MaskValue(val, reveal, '*')
+	}
+	return defaultValue
+}
+
+// GetSecretValidated retrieves a secret such as an API key and panics if it
+// is shorter than minLen characters. The error never includes the value, so
+// the secret does not end up in logs or crash reports.
+func GetSecretValidated(key string, minLen int, defaultValue string) string {
+	if val, ok := lookup(key); ok {
+		if utf8.RuneCountInString(val) < minLen {
+			parseFailed(key, fmt.Errorf("Environment variable %s must be at least %d characters long", key, minLen))
+			return defaultValue
+		}
+		return val
+	}
+	return defaultValue
+}
+
+// MaskValue replaces all but the last reveal characters of val with maskChar.
+// If reveal is not smaller than the length of val, the whole value is masked
+// so a short secret is never printed in full.
+func MaskValue(val string, reveal int, maskChar rune) string {
+	runes := []rune(val)
+	if reveal < 0 || reveal >= len(runes) {
+		reveal = 0
+	}
+	masked := len(runes) - reveal
+	return strings.Repeat(string(maskChar), masked) + string(runes[masked:])
+}
+
+// errorHandler receives parse failures when registered via SetErrorHandler.
+var errorHandler func(key string, err error)
+
+// SetErrorHandler registers a handler that getters call when a value exists
+// but cannot be parsed. The getter then returns its default value instead of
+// panicking. Passing nil restores the default panic behavior.
+func SetErrorHandler(handler func(key string, err error)) {
+	errorHandler = handler
+}
+
+// parseFailed reports a parse failure for key to the registered error
+// handler, or panics with the error message if no handler is set.
+func parseFailed(key string, err error) {
+	if errorHandler != nil {
+		errorHandler(key, err)
+		return
+	}
+	panic(err.Error())
+}
+
+// GetEnvMatching returns all variables whose names match the given glob
+// pattern, using filepath.Match semantics against the merged set of OS and
+// *.env keys. OS values take precedence over values loaded from files.
+// Panics if the pattern is malformed.
+func GetEnvMatching(pattern string) map[string]string {
+	result := make(map[string]string)
+	for key, val := range environ() {
+		matched, err := filepath.Match(pattern, key)
+		if err != nil {
+			panic(fmt.Sprintf("Invalid environment key pattern %s: %v", pattern, err))
+		}
+		if matched {
+			result[key] = val
+		}
+	}
+	return result
+}
+
+// environ returns the merged view of variables loaded from *.env files, the
+// OS environment (or its snapshot) and overrides, in increasing precedence.
+func environ() map[string]string {
+	loaded := loadedEnv()
+	merged := make(map[string]string, len(loaded))
+	for key, val := range loaded {
+		merged[key] = val
+	}
+	if snapshot := osSnapshot.Load(); snapshot != nil {
+		for key, val := range *snapshot {
+			merged[key] = val
+		}
+	} else {
+		for key, val := range parseEnviron(environOS()) {
+			merged[key] = val
+		}
+	}
+	for key, val := range overrides() {
+		merged[key] = val
+	}
+	return merged
+}
+
+// GetEnvArrayString retrieves a string slice from a delimited environment variable or returns the default.
+func GetEnvArrayString(key string, split string, defaultValue []string, opts ...ArrayOption) []string {
+	if val := GetEnvString(key, ""); val != "" {
+		values, err := splitArray(key, val, split, opts)
+		if err != nil {
+			parseFailed(key, err)
+			return defaultValue
+		}
+		return values
+	}
+	return defaultValue
+}
+
+// GetEnvIndexMap retrieves a delimited environment variable as a map from each
+// element to its position, e.g. for priority ordering. If an element occurs
+// more than once, the index of its first occurrence is kept.
+func GetEnvIndexMap(key, split string, defaultValue map[string]int) map[string]int {
+	if val := GetEnvString(key, ""); val != "" {
+		result := make(map[string]int)
+		for i, v := range strings.Split(val, split) {
+			if _, exists := result[v]; !exists {
+				result[v] = i
+			}
+		}
+		return result
+	}
+	return defaultValue
+}
+
+// RangeEnvArrayString splits a delimited environment variable lazily and calls
+// fn with each element and its index, stopping early if fn returns false.
+// Unlike GetEnvArrayString it never allocates the full slice.
+func RangeEnvArrayString(key, split string, fn func(i int, v string) bool) {
+	val := GetEnvString(key, "")
+	if val == "" {
+		return
+	}
+	i := 0
+	for v := range strings.SplitSeq(val, split) {
+		if !fn(i, v) {
+			return
+		}
+		i++
+	}
+}
+
+// GetEnvArrayStringLimited retrieves a delimited environment variable and
+// truncates it to the number of elements configured in limitKey, e.g.
+// WORKERS_MAX capping WORKERS_LIST. defaultLimit applies when limitKey is not
+// set; a negative limit disables truncation.
+// Panics if limitKey exists but is not a valid integer.
+func GetEnvArrayStringLimited(key, split, limitKey string, defaultLimit int, defaultValue []string) []string {
+	values := GetEnvArrayString(key, split, defaultValue)
+	if limit := GetEnvInt(limitKey, defaultLimit); limit >= 0 && limit < len(values) {
+		return values[:limit]
+	}
+	return values
+}
+
+// JoinArray serializes values back into delimited form, quoting elements that
+// contain the delimiter or a quote so that GetEnvArrayStringQuoted reads them
+// back unchanged. Plain elements are written as-is.
+func JoinArray(values []string, split string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		if (split == "" || !strings.Contains(v, split)) && !strings.ContainsAny(v, `"'`) {
+			quoted[i] = v
+			continue
+		}
+		// Double quotes inside the element are emitted as '"' segments
+		quoted[i] = `"` + strings.ReplaceAll(v, `"`, `"'"'"`) + `"`
+	}
+	return strings.Join(quoted, split)
+}
+
+// GetEnvArrayStringAt retrieves the trimmed element at index from a delimited
+// environment variable, or returns the default if the variable is not set or
+// the index is out of range.
+func GetEnvArrayStringAt(key, split string, index int, defaultValue string) string {
+	values := GetEnvArrayString(key, split, nil)
+	if index < 0 || index >= len(values) {
+		return defaultValue
+	}
+	return strings.TrimSpace(values[index])
+}
+
+// GetEnvArrayStringQuoted retrieves a delimited environment variable as a string
+// slice, treating single- or double-quoted segments atomically so they may
+// contain the delimiter, e.g. ITEMS="a,b",c yields [a,b c]. Quotes are stripped.
+// Quotes inside an element, as in O'Brien, are kept as ordinary characters.
+// Panics if a quote is left unterminated.
+func GetEnvArrayStringQuoted(key, split string, defaultValue []string) []string {
+	if val := GetEnvString(key, ""); val != "" {
+		values, err := splitQuoted(val, split)
+		if err != nil {
+			parseFailed(key, fmt.Errorf("Environment variable %s is not a valid quoted list: %v", key, err))
+			return defaultValue
+		}
+		return values
+	}
+	return defaultValue
+}
+
+// splitQuoted splits val on split, ignoring delimiters inside quotes. A quote
+// only opens a quoted segment at the start of an element (after optional
+// whitespace) or right after a closing quote; elsewhere, as in O'Brien, it is
+// an ordinary character.
+func splitQuoted(val, split string) ([]string, error) {
+	var values []string
+	var current strings.Builder
+	var quote byte
+	literal := false
+	for i := 0; i < len(val); i++ {
+		c := val[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				current.WriteByte(c)
+			}
+		case (c == '"' || c == '\'') && !literal:
+			quote = c
+		case split != "" && strings.HasPrefix(val[i:], split):
+			values = append(values, current.String())
+			current.Reset()
+			literal = false
+			i += len(split) - 1
+		default:
+			current.WriteByte(c)
+			if c != ' ' && c != '\t' {
+				literal = true
+			}
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in %q", val)
+	}
+	return append(values, current.String()), nil
+}
+
+// AttributedValue is a list element with optional per-element attributes,
+// as returned by GetEnvArrayAttributed.
+type AttributedValue struct {
+	Value string
+	Attrs map[string]string
+}
+
+// GetEnvArrayAttributed retrieves a delimited environment variable whose elements
+// carry attributes, e.g. BACKENDS=host1|weight=5,host2|weight=3. Each element is
+// split on attrSplit; the first part is the value and the rest are attributes
+// in key/value form separated by kvSplit.
+// Panics if an attribute doesn't contain the key-value delimiter.
+func GetEnvArrayAttributed(key, split, attrSplit, kvSplit string, defaultValue []AttributedValue) []AttributedValue {
+	if val := GetEnvString(key, ""); val != "" {
+		elements := strings.Split(val, split)
+		result := make([]AttributedValue, 0, len(elements))
+		for _, element := range elements {
+			parts := strings.Split(element, attrSplit)
+			item := AttributedValue{
+				Value: strings.TrimSpace(parts[0]),
+				Attrs: make(map[string]string, len(parts)-1),
+			}
+			for _, attr := range parts[1:] {
+				kv := strings.SplitN(attr, kvSplit, 2)
+				if len(kv) != 2 {
+					parseFailed(key, fmt.Errorf("Environment variable %s contains invalid attribute: %s", key, attr))
+					return defaultValue
+				}
+				item.Attrs[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+			}
+			result = append(result, item)
+		}
+		return result
+	}
+	return defaultValue
+}
+
+// GetEnvGlob retrieves an environment variable holding a file pattern, e.g.
+// CONFIGS=/etc/app/*.yaml, and returns the paths matching it. An empty slice
+// is returned if nothing matches.
+// Panics if the value exists but is not a valid pattern.
+func GetEnvGlob(key string, defaultValue []string) []string {
+	if val := GetEnvString(key, ""); val != "" {
+		matches, err := filepath.Glob(val)
+		if err != nil {
+			parseFailed(key, fmt.Errorf("Environment variable %s is not a valid glob pattern: %v", key, err))
+			return defaultValue
+		}
+		if matches == nil {
+			return []string{}
+		}
+		return matches
+	}
+	return defaultValue
+}
+
+// GetEnvArrayStringChunks retrieves a delimited environment variable and groups
+// its elements into sub-slices of chunkSize. The last chunk holds any remainder.
+// Panics if chunkSize is not positive.
+func GetEnvArrayStringChunks(key, split string, chunkSize int, defaultValue [][]string) [][]string {
+	if chunkSize <= 0 {
+		panic(fmt.Sprintf("Invalid chunk size %d for environment variable %s", chunkSize, key))
+	}
+	if val := GetEnvString(key, ""); val != "" {
+		values := strings.Split(val, split)
+		chunks := make([][]string, 0, (len(values)+chunkSize-1)/chunkSize)
+		for len(values) > chunkSize {
+			chunks = append(chunks, values[:chunkSize:chunkSize])
+			values = values[chunkSize:]
+		}
+		return append(chunks, values)
+	}
+	return defaultValue
+}
+
+// GetEnvInt retrieves an environment variable's value as an integer.
+// Panics if the value exists but is not a valid integer.
+func GetEnvInt(key string, defaultValue int) int {
+	if val := GetEnvString(key, ""); val != "" {
+		intValue, err := strconv.Atoi(val)
+		if err != nil {
+			parseFailed(key, fmt.Errorf("Environment variable %s is not a valid integer: %v", key, err))
+			return defaultValue
+		}
+		return intValue
+	}
+	return defaultValue
+}
+
+// GetEnvUint retrieves an environment variable's value as an unsigned
+// integer. Negative and out-of-range values are rejected rather than wrapped.
+// Panics if the value exists but is not a valid unsigned integer.
+func GetEnvUint(key string, defaultValue uint) uint {
+	if val := GetEnvString(key, ""); val != "" {
+		uintValue, err := strconv.ParseUint(val, 10, 0)
+		if err != nil {
+			parseFailed(key, fmt.Errorf("Environment variable %s is not a valid unsigned integer: %v", key, err))
+			return defaultValue
+		}
+		return uint(uintValue)
+	}
+	return defaultValue
+}
+
+// GetEnvIntSep retrieves an environment variable's value as an integer,
+// allowing Go-style underscore digit separators such as 1_000_000.
+// Panics if the value exists but is not a valid integer or a separator is misplaced.
+func GetEnvIntSep(key string, defaultValue int) int {
+	if val := GetEnvString(key, ""); val != "" {
+		digits, err := stripDigitSeparators(val)
+		if err != nil {
+			parseFailed(key, fmt.Errorf("Environment variable %s is not a valid integer: %v", key, err))
+			return defaultValue
+		}
+		intValue, err := strconv.Atoi(digits)
+		if err != nil {
+			parseFailed(key, fmt.Errorf("Environment variable %s is not a valid integer: %v", key, err))
+			return defaultValue
+		}
+		return intValue
+	}
+	return defaultValue
+}
+
+// GetEnvIntBase retrieves an environment variable's value as an integer in
+// the given base, e.g. 16 for FLAGS=FF or 2 for MASK=1010. Base 0 infers the
+// base from a 0x, 0b or 0o prefix as GetEnvIntAuto does.
+// Panics if the value exists but is not a valid integer in that base.
+func GetEnvIntBase(key string, base int, defaultValue int) int {
+	if val := GetEnvString(key, ""); val != "" {
+		intValue, err := strconv.ParseInt(val, base, 0)
+		if err != nil {
+			parseFailed(key, fmt.Errorf("Environment variable %s is not a valid integer: %v", key, err))
+			return defaultValue
+		}
+		return int(intValue)
+	}
+	return defaultValue
+}
+
+// GetEnvIntAuto retrieves an environment variable's value as an integer,
+// reading hex, binary and octal literals such as 0xFF, 0b1010 and 0o755 by
+// their prefix and anything else as decimal.
+// Panics if the value exists but is not a valid integer literal.
+func GetEnvIntAuto(key string, defaultValue int) int {
+	return GetEnvIntBase(key, 0, defaultValue)
+}
+
+// stripDigitSeparators removes underscores placed between two digits.
+// Leading, trailing or consecutive underscores are rejected.
+func stripDigitSeparators(val string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(val); i++ {
+		if val[i] != '_' {
+			b.WriteByte(val[i])
+			continue
+		}
+		if i == 0 || i == len(val)-1 || !isDigit(val[i-1]) || !isDigit(val[i+1]) {
+			return "", fmt.Errorf("misplaced digit separator in %q", val)
+		}
+	}
+	return b.String(), nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// GetEnvDuration retrieves an environment variable's value as a time.Duration.
+// Panics if the value exists but is not a valid duration.
+func GetEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if val := GetEnvString(key, ""); val != "" {
+		durationValue, err := time.ParseDuration(val)
+		if err != nil {
+			parseFailed(key, fmt.Errorf("Environment variable %s is not a valid duration: %v", key, err))
+			return defaultValue
+		}
+		return durationValue
+	}
+	return defaultValue
+}
+
+// jitterRand is the random source used by GetEnvDurationJitter once seeded
+// via SeedJitter. When nil, the global math/rand/v2 source is used.
+var (
+	jitterRand *rand.Rand
+	jitterMu   sync.Mutex
 )
 
-// envMap stores environment variables loaded from *.env files at runtime.
-// Variables from the OS environment (os.Getenv) take precedence over these.
-var envMap = make(map[string]string)
+// SeedJitter makes GetEnvDurationJitter deterministic by seeding its random
+// source, which is useful in tests. Reset restores the unseeded global source.
+func SeedJitter(seed uint64) {
+	jitterMu.Lock()
+	defer jitterMu.Unlock()
+	jitterRand = rand.New(rand.NewPCG(seed, seed))
+}
 
-// init loads all environment variables from *.env files located in the same
-// directory as the compiled binary. These variables are stored in memory
-// (envMap) and are only used if the variable is not present in the system
-// environment (os.Getenv). Variables are never written into the system
-// environment to avoid exposure.
-func init() {
-	exePath, err := os.Executable()
-	if err != nil {
-		return
+// GetEnvDurationJitter retrieves an environment variable's value as a
+// time.Duration and randomizes it within ±jitterFraction of itself, e.g. a
+// fraction of 0.1 turns 1s into a value between 900ms and 1.1s. The jitter is
+// applied to the default value as well.
+// Panics if the value exists but is not a valid duration.
+func GetEnvDurationJitter(key string, defaultValue time.Duration, jitterFraction float64) time.Duration {
+	base := GetEnvDuration(key, defaultValue)
+	if jitterFraction <= 0 {
+		return base
 	}
-	dir := filepath.Dir(exePath)
 
-	// Discover all *.env files in the binary directory
-	files, err := filepath.Glob(filepath.Join(dir, "*.env"))
-	if err != nil {
-		return
+	jitterMu.Lock()
+	var r float64
+	if jitterRand != nil {
+		r = jitterRand.Float64()
+	} else {
+		r = rand.Float64()
 	}
+	jitterMu.Unlock()
 
-	// Parse each .env file line-by-line
-	for _, file := range files {
-		f, err := os.Open(file)
+	// Map r from [0, 1) onto [-jitterFraction, +jitterFraction)
+	spread := (2*r - 1) * jitterFraction
+	return base + time.Duration(float64(base)*spread)
+}
+
+// GetEnvDurationHuman retrieves an environment variable's value as a time.Duration,
+// extending time.ParseDuration with "d" (24h) and "w" (168h) units, e.g. 30d or 2w1d.
+// Panics if the value exists but is not a valid duration.
+func GetEnvDurationHuman(key string, defaultValue time.Duration) time.Duration {
+	if val := GetEnvString(key, ""); val != "" {
+		durationValue, err := parseHumanDuration(val)
 		if err != nil {
-			continue
+			parseFailed(key, fmt.Errorf("Environment variable %s is not a valid duration: %v", key, err))
+			return defaultValue
 		}
-		defer f.Close()
+		return durationValue
+	}
+	return defaultValue
+}
 
-		scanner := bufio.NewScanner(f)
-		for scanner.Scan() {
-			line := strings.TrimSpace(scanner.Text())
+// humanUnits holds the units understood on top of time.ParseDuration.
+var humanUnits = map[string]time.Duration{
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
 
-			// Ignore empty lines and comments
-			if line == "" || strings.HasPrefix(line, "#") {
-				continue
-			}
+// parseHumanDuration parses a duration string such as "1w2d3h". Segments using
+// day or week units are scaled here; all other segments go to time.ParseDuration.
+// Like time.ParseDuration, it fails if the total does not fit a time.Duration.
+func parseHumanDuration(val string) (time.Duration, error) {
+	s := val
+	negative := false
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		negative = s[0] == '-'
+		s = s[1:]
+	}
+	if s == "0" {
+		return 0, nil
+	}
+	if s == "" {
+		return 0, fmt.Errorf("invalid duration %q", val)
+	}
 
-			// Parse key=value pairs
-			kv := strings.SplitN(line, "=", 2)
-			if len(kv) != 2 {
-				continue
-			}
-			key := strings.TrimSpace(kv[0])
-			val := strings.TrimSpace(kv[1])
+	var total time.Duration
+	for s != "" {
+		i := 0
+		for i < len(s) && (isDigit(s[i]) || s[i] == '.') {
+			i++
+		}
+		j := i
+		for j < len(s) && !isDigit(s[j]) && s[j] != '.' {
+			j++
+		}
+		number, unit := s[:i], s[i:j]
+		if number == "" || unit == "" {
+			return 0, fmt.Errorf("invalid duration %q", val)
+		}
 
-			// Only load the value if it's not already in the system environment
-			if _, exists := os.LookupEnv(key); !exists {
-				envMap[key] = val
+		var d time.Duration
+		var err error
+		if scale, ok := humanUnits[unit]; ok {
+			var f float64
+			f, err = strconv.ParseFloat(number, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", val)
 			}
+			scaled := f * float64(scale)
+			if scaled >= math.MaxInt64 {
+				return 0, fmt.Errorf("duration %q out of range", val)
+			}
+			d = time.Duration(scaled)
+		} else {
+			d, err = time.ParseDuration(number + unit)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", val)
+			}
+		}
+
+		// Segments are never negative, so only the upper bound can be exceeded
+		if total > math.MaxInt64-d {
+			return 0, fmt.Errorf("duration %q out of range", val)
 		}
+		total += d
+		s = s[j:]
+	}
+
+	if negative {
+		total = -total
 	}
+	return total, nil
 }
 
-// GetEnvString retrieves an environment variable's value as a string.
-// It first checks the OS environment, then loaded *.env files, and finally falls back to the default.
-func GetEnvString(key, defaultValue string) string {
-	if val, ok := os.LookupEnv(key); ok {
-		return val
+// trueValues and falseValues are the extra boolean words set via
+// SetBoolValues, keyed by their lowercase form.
+var trueValues, falseValues map[string]bool
+
+// SetBoolValues adds words that boolean getters and Unmarshal accept as true
+// or false, e.g. "si" and "no" for Spanish deployments. Words are matched
+// case-insensitively and checked before the standard forms accepted by
+// strconv.ParseBool, which keep working. Values in neither set still fail to
+// parse. Call Reset to remove the custom words.
+func SetBoolValues(trueVals, falseVals []string) {
+	trueValues = make(map[string]bool, len(trueVals))
+	for _, v := range trueVals {
+		trueValues[strings.ToLower(v)] = true
 	}
-	if val, ok := envMap[key]; ok {
-		return val
+	falseValues = make(map[string]bool, len(falseVals))
+	for _, v := range falseVals {
+		falseValues[strings.ToLower(v)] = true
 	}
-	return defaultValue
+	invalidateCache()
 }
 
-// GetEnvArrayString retrieves a string slice from a delimited environment variable or returns the default.
-func GetEnvArrayString(key string, split string, defaultValue []string) []string {
+// parseBool parses val using the words set via SetBoolValues, then the
+// forms accepted by strconv.ParseBool.
+func parseBool(val string) (bool, error) {
+	word := strings.ToLower(val)
+	if trueValues[word] {
+		return true, nil
+	}
+	if falseValues[word] {
+		return false, nil
+	}
+	return strconv.ParseBool(val)
+}
+
+// GetEnvBool retrieves an environment variable's value as a boolean.
+// Panics if the value exists but is not a valid boolean.
+func GetEnvBool(key string, defaultValue bool) bool {
 	if val := GetEnvString(key, ""); val != "" {
-		return strings.Split(val, split)
+		boolValue, err := parseBool(val)
+		if err != nil {
+			parseFailed(key, fmt.Errorf("Environment variable %s is not a valid boolean: %v", key, err))
+			return defaultValue
+		}
+		return boolValue
 	}
 	return defaultValue
 }
 
-// GetEnvInt retrieves an environment variable's value as an integer.
+// GetEnvBoolPtr retrieves an environment variable's value as a *bool for
+// tri-state flags, returning nil if the variable is not set.
+// Panics if the value exists but is not a valid boolean.
+func GetEnvBoolPtr(key string) *bool {
+	if val := GetEnvString(key, ""); val != "" {
+		boolValue, err := parseBool(val)
+		if err != nil {
+			parseFailed(key, fmt.Errorf("Environment variable %s is not a valid boolean: %v", key, err))
+			return nil
+		}
+		return &boolValue
+	}
+	return nil
+}
+
+// GetEnvStringPtr retrieves an environment variable's value as a *string,
+// returning nil if the variable is not set. A variable explicitly set to an
+// empty string yields a pointer to "".
+func GetEnvStringPtr(key string) *string {
+	if val, ok := lookup(key); ok {
+		return &val
+	}
+	return nil
+}
+
+// GetEnvIntPtr retrieves an environment variable's value as an *int,
+// returning nil if the variable is not set.
 // Panics if the value exists but is not a valid integer.
-func GetEnvInt(key string, defaultValue int) int {
+func GetEnvIntPtr(key string) *int {
 	if val := GetEnvString(key, ""); val != "" {
 		intValue, err := strconv.Atoi(val)
 		if err != nil {
-			panic(fmt.Sprintf("Environment variable %s is not a valid integer: %v", key, err))
+			parseFailed(key, fmt.Errorf("Environment variable %s is not a valid integer: %v", key, err))
+			return nil
 		}
-		return intValue
+		return &intValue
 	}
-	return defaultValue
+	return nil
 }
 
-// GetEnvDuration retrieves an environment variable's value as a time.Duration.
+// GetEnvFloat64Ptr retrieves an environment variable's value as a *float64,
+// returning nil if the variable is not set.
+// Panics if the value exists but is not a valid float64.
+func GetEnvFloat64Ptr(key string) *float64 {
+	if val := GetEnvString(key, ""); val != "" {
+		floatValue, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			parseFailed(key, fmt.Errorf("Environment variable %s is not a valid float64: %v", key, err))
+			return nil
+		}
+		return &floatValue
+	}
+	return nil
+}
+
+// GetEnvDurationPtr retrieves an environment variable's value as a
+// *time.Duration, returning nil if the variable is not set.
 // Panics if the value exists but is not a valid duration.
-func GetEnvDuration(key string, defaultValue time.Duration) time.Duration {
+func GetEnvDurationPtr(key string) *time.Duration {
 	if val := GetEnvString(key, ""); val != "" {
 		durationValue, err := time.ParseDuration(val)
 		if err != nil {
-			panic(fmt.Sprintf("Environment variable %s is not a valid duration: %v", key, err))
+			parseFailed(key, fmt.Errorf("Environment variable %s is not a valid duration: %v", key, err))
+			return nil
 		}
-		return durationValue
+		return &durationValue
 	}
-	return defaultValue
+	return nil
 }
 
-// GetEnvBool retrieves an environment variable's value as a boolean.
+// GetEnvBoolStrict retrieves an environment variable's value as a boolean,
+// accepting only "true" or "false" in any letter case. Unlike GetEnvBool it
+// rejects shorthand forms such as 1, 0, t or f.
 // Panics if the value exists but is not a valid boolean.
-func GetEnvBool(key string, defaultValue bool) bool {
+func GetEnvBoolStrict(key string, defaultValue bool) bool {
 	if val := GetEnvString(key, ""); val != "" {
-		boolValue, err := strconv.ParseBool(val)
-		if err != nil {
-			panic(fmt.Sprintf("Environment variable %s is not a valid boolean: %v", key, err))
+		switch strings.ToLower(val) {
+		case "true":
+			return true
+		case "false":
+			return false
 		}
-		return boolValue
+		parseFailed(key, fmt.Errorf("Environment variable %s is not a strict boolean: %s", key, val))
+		return defaultValue
 	}
 	return defaultValue
 }
@@ -130,7 +1885,8 @@ func GetEnvFloat64(key string, defaultValue float64) float64 {
 	if val := GetEnvString(key, ""); val != "" {
 		floatValue, err := strconv.ParseFloat(val, 64)
 		if err != nil {
-			panic(fmt.Sprintf("Environment variable %s is not a valid float64: %v", key, err))
+			parseFailed(key, fmt.Errorf("Environment variable %s is not a valid float64: %v", key, err))
+			return defaultValue
 		}
 		return floatValue
 	}
@@ -139,14 +1895,19 @@ func GetEnvFloat64(key string, defaultValue float64) float64 {
 
 // GetEnvArrayInt retrieves an environment variable's value as a slice of integers.
 // Panics if any value in the slice is not a valid integer.
-func GetEnvArrayInt(key string, split string, defaultValue []int) []int {
+func GetEnvArrayInt(key string, split string, defaultValue []int, opts ...ArrayOption) []int {
 	if val := GetEnvString(key, ""); val != "" {
-		stringValues := strings.Split(val, split)
+		stringValues, err := splitArray(key, val, split, opts)
+		if err != nil {
+			parseFailed(key, err)
+			return defaultValue
+		}
 		intValues := make([]int, 0, len(stringValues))
 		for _, str := range stringValues {
 			intValue, err := strconv.Atoi(str)
 			if err != nil {
-				panic(fmt.Sprintf("Environment variable %s array contains an invalid integer: %s", key, str))
+				parseFailed(key, fmt.Errorf("Environment variable %s array contains an invalid integer: %s", key, str))
+				return defaultValue
 			}
 			intValues = append(intValues, intValue)
 		}
@@ -155,16 +1916,130 @@ func GetEnvArrayInt(key string, split string, defaultValue []int) []int {
 	return defaultValue
 }
 
+// GetEnvArrayPort retrieves an environment variable's value as a slice of
+// network ports, e.g. PORTS=8080,8081,8082.
+// Panics if any value in the slice is not an integer in the range [0, 65535].
+func GetEnvArrayPort(key string, split string, defaultValue []int, opts ...ArrayOption) []int {
+	if val := GetEnvString(key, ""); val != "" {
+		stringValues, err := splitArray(key, val, split, opts)
+		if err != nil {
+			parseFailed(key, err)
+			return defaultValue
+		}
+		ports := make([]int, 0, len(stringValues))
+		for _, str := range stringValues {
+			port, err := strconv.Atoi(str)
+			if err != nil || port < 0 || port > 65535 {
+				parseFailed(key, fmt.Errorf("Environment variable %s array contains an invalid port: %s", key, str))
+				return defaultValue
+			}
+			ports = append(ports, port)
+		}
+		return ports
+	}
+	return defaultValue
+}
+
+// GetEnvArrayEmail retrieves an environment variable's value as a slice of
+// email addresses, e.g. ADMINS=a@x.com, b@y.com. Elements are trimmed and
+// may use the display-name form "Jane Doe <jane@x.com>", which is kept as is.
+// Panics if any element is not a valid address.
+func GetEnvArrayEmail(key string, split string, defaultValue []string, opts ...ArrayOption) []string {
+	if val := GetEnvString(key, ""); val != "" {
+		stringValues, err := splitArray(key, val, split, opts)
+		if err != nil {
+			parseFailed(key, err)
+			return defaultValue
+		}
+		addresses := make([]string, 0, len(stringValues))
+		for _, str := range stringValues {
+			str = strings.TrimSpace(str)
+			if _, err := mail.ParseAddress(str); err != nil {
+				parseFailed(key, fmt.Errorf("Environment variable %s array contains an invalid email address: %s", key, str))
+				return defaultValue
+			}
+			addresses = append(addresses, str)
+		}
+		return addresses
+	}
+	return defaultValue
+}
+
+// ElementError reports an array element that could not be parsed, together
+// with its position in the list.
+type ElementError struct {
+	Index int
+	Value string
+	Err   error
+}
+
+func (e *ElementError) Error() string {
+	return fmt.Sprintf("element %d (%q): %v", e.Index, e.Value, e.Err)
+}
+
+func (e *ElementError) Unwrap() error {
+	return e.Err
+}
+
+// LookupEnvArrayInt retrieves an environment variable's value as a slice of
+// integers, returning nil if the variable is not set. Instead of panicking, a
+// parse failure is returned as an *ElementError identifying the element.
+func LookupEnvArrayInt(key, split string) ([]int, error) {
+	val := GetEnvString(key, "")
+	if val == "" {
+		return nil, nil
+	}
+	stringValues := strings.Split(val, split)
+	intValues := make([]int, 0, len(stringValues))
+	for i, str := range stringValues {
+		intValue, err := strconv.Atoi(str)
+		if err != nil {
+			return nil, &ElementError{Index: i, Value: str, Err: err}
+		}
+		intValues = append(intValues, intValue)
+	}
+	return intValues, nil
+}
+
 // GetEnvArrayDuration retrieves an environment variable's value as a slice of time.Duration values.
 // Panics if any value in the slice is not a valid duration.
-func GetEnvArrayDuration(key string, split string, defaultValue []time.Duration) []time.Duration {
+func GetEnvArrayDuration(key string, split string, defaultValue []time.Duration, opts ...ArrayOption) []time.Duration {
 	if val := GetEnvString(key, ""); val != "" {
-		stringValues := strings.Split(val, split)
+		stringValues, err := splitArray(key, val, split, opts)
+		if err != nil {
+			parseFailed(key, err)
+			return defaultValue
+		}
 		durationValues := make([]time.Duration, 0, len(stringValues))
 		for _, str := range stringValues {
 			durationValue, err := time.ParseDuration(str)
 			if err != nil {
-				panic(fmt.Sprintf("Environment variable %s array contains an invalid duration: %s", key, str))
+				parseFailed(key, fmt.Errorf("Environment variable %s array contains an invalid duration: %s", key, str))
+				return defaultValue
+			}
+			durationValues = append(durationValues, durationValue)
+		}
+		return durationValues
+	}
+	return defaultValue
+}
+
+// GetEnvArrayDurationHuman retrieves an environment variable's value as a slice of
+// time.Duration values, accepting the "d" and "w" units of GetEnvDurationHuman.
+// Panics if any value in the slice is not a valid duration.
+func GetEnvArrayDurationHuman(key string, split string, defaultValue []time.Duration, opts ...ArrayOption) []time.Duration {
+	if val := GetEnvString(key, ""); val != "" {
+		stringValues, err := splitArray(key, val, split, opts)
+		if err != nil {
+			parseFailed(key, err)
+			return defaultValue
+		}
+		durationValues := make([]time.Duration, 0, len(stringValues))
+		for _, str := range stringValues {
+			durationValue, err := parseHumanDuration(str)
+			if err != nil {
+				parseFailed(key, fmt.Errorf("Environment variable %s array contains an invalid duration: %s", key, str))
+				return defaultValue
 			}
 			durationValues = append(durationValues, durationValue)
 		}
@@ -183,7 +2058,8 @@ func GetEnvMapStringString(key string, entryDelimiter string, kvDelimiter string
 		for _, entry := range entries {
 			kv := strings.SplitN(entry, kvDelimiter, 2)
 			if len(kv) != 2 {
-				panic(fmt.Sprintf("Environment variable %s contains invalid map entry: %s", key, entry))
+				parseFailed(key, fmt.Errorf("Environment variable %s contains invalid map entry: %s", key, entry))
+				return defaultValue
 			}
 			result[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
 		}
@@ -191,3 +2067,273 @@ func GetEnvMapStringString(key string, entryDelimiter string, kvDelimiter string
 	}
 	return defaultValue
 }
+
+// mapEntryDelimiter and mapKVDelimiter are the delimiters GetEnvMap splits
+// on, set via SetMapDelimiters.
+var (
+	mapEntryDelimiter = DefaultDelimiter
+	mapKVDelimiter    = ":"
+)
+
+// SetMapDelimiters sets the entry and key-value delimiters used by GetEnvMap,
+// which default to "," and ":". Call Reset to restore the defaults.
+func SetMapDelimiters(entry, kv string) {
+	mapEntryDelimiter = entry
+	mapKVDelimiter = kv
+}
+
+// GetEnvMap works like GetEnvMapStringString using the delimiters set via
+// SetMapDelimiters, so call sites only pass the key and default.
+func GetEnvMap(key string, defaultValue map[string]string) map[string]string {
+	return GetEnvMapStringString(key, mapEntryDelimiter, mapKVDelimiter, defaultValue)
+}
+
+// GetEnvMapStringDuration retrieves an environment variable as a map of names
+// to durations, e.g. TIMEOUTS=read:5s,write:10s. Panics if any entry is
+// malformed or has a value that is not a valid duration.
+func GetEnvMapStringDuration(key, entryDelim, kvDelim string, defaultValue map[string]time.Duration) map[string]time.Duration {
+	if val := GetEnvString(key, ""); val != "" {
+		result := make(map[string]time.Duration)
+		for _, entry := range strings.Split(val, entryDelim) {
+			kv := strings.SplitN(entry, kvDelim, 2)
+			if len(kv) != 2 {
+				parseFailed(key, fmt.Errorf("Environment variable %s contains invalid map entry: %s", key, entry))
+				return defaultValue
+			}
+			d, err := time.ParseDuration(strings.TrimSpace(kv[1]))
+			if err != nil {
+				parseFailed(key, fmt.Errorf("Environment variable %s contains an invalid duration value: %s", key, entry))
+				return defaultValue
+			}
+			result[strings.TrimSpace(kv[0])] = d
+		}
+		return result
+	}
+	return defaultValue
+}
+
+// GetEnvMapStringBool retrieves an environment variable as a map of names
+// to booleans, e.g. FEATURES=f1:true,f2:false. Panics if any entry is malformed or has a value
+// that is not a valid boolean.
+func GetEnvMapStringBool(key, entryDelim, kvDelim string, defaultValue map[string]bool) map[string]bool {
+	if val := GetEnvString(key, ""); val != "" {
+		result := make(map[string]bool)
+		for _, entry := range strings.Split(val, entryDelim) {
+			kv := strings.SplitN(entry, kvDelim, 2)
+			if len(kv) != 2 {
+				parseFailed(key, fmt.Errorf("Environment variable %s contains invalid map entry: %s", key, entry))
+				return defaultValue
+			}
+			parsed, err := parseBool(strings.TrimSpace(kv[1]))
+			if err != nil {
+				parseFailed(key, fmt.Errorf("Environment variable %s contains an invalid boolean value: %s", key, entry))
+				return defaultValue
+			}
+			result[strings.TrimSpace(kv[0])] = parsed
+		}
+		return result
+	}
+	return defaultValue
+}
+
+// GetEnvMapStringFloat64 retrieves an environment variable as a map of names
+// to float64 values, e.g. WEIGHTS=a:0.7,b:0.3. Panics if any entry is malformed or has a value
+// that is not a valid float64.
+func GetEnvMapStringFloat64(key, entryDelim, kvDelim string, defaultValue map[string]float64) map[string]float64 {
+	if val := GetEnvString(key, ""); val != "" {
+		result := make(map[string]float64)
+		for _, entry := range strings.Split(val, entryDelim) {
+			kv := strings.SplitN(entry, kvDelim, 2)
+			if len(kv) != 2 {
+				parseFailed(key, fmt.Errorf("Environment variable %s contains invalid map entry: %s", key, entry))
+				return defaultValue
+			}
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64)
+			if err != nil {
+				parseFailed(key, fmt.Errorf("Environment variable %s contains an invalid float64 value: %s", key, entry))
+				return defaultValue
+			}
+			result[strings.TrimSpace(kv[0])] = parsed
+		}
+		return result
+	}
+	return defaultValue
+}
+
+// GetEnvMapIntString retrieves an environment variable as a map keyed by
+// integers, e.g. HTTP_MESSAGES=404:not found,500:server error. Panics if any
+// entry is malformed or has a key that is not a valid integer.
+func GetEnvMapIntString(key, entryDelim, kvDelim string, defaultValue map[int]string) map[int]string {
+	if val := GetEnvString(key, ""); val != "" {
+		result := make(map[int]string)
+		for _, entry := range strings.Split(val, entryDelim) {
+			kv := strings.SplitN(entry, kvDelim, 2)
+			if len(kv) != 2 {
+				parseFailed(key, fmt.Errorf("Environment variable %s contains invalid map entry: %s", key, entry))
+				return defaultValue
+			}
+			k, err := strconv.Atoi(strings.TrimSpace(kv[0]))
+			if err != nil {
+				parseFailed(key, fmt.Errorf("Environment variable %s contains an invalid integer key: %s", key, entry))
+				return defaultValue
+			}
+			result[k] = strings.TrimSpace(kv[1])
+		}
+		return result
+	}
+	return defaultValue
+}
+
+// GetEnvSortedByValueInt retrieves an environment variable as key-value pairs
+// with integer values, e.g. TASKS=b:2,a:1,c:3, and returns the keys sorted
+// ascending by value. Keys with equal values are ordered by name.
+// Panics if any entry is malformed or has a non-numeric value.
+func GetEnvSortedByValueInt(key, entryDelim, kvDelim string, defaultValue []string) []string {
+	if val := GetEnvString(key, ""); val != "" {
+		priorities := make(map[string]int)
+		for _, entry := range strings.Split(val, entryDelim) {
+			kv := strings.SplitN(entry, kvDelim, 2)
+			if len(kv) != 2 {
+				parseFailed(key, fmt.Errorf("Environment variable %s contains invalid map entry: %s", key, entry))
+				return defaultValue
+			}
+			priority, err := strconv.Atoi(strings.TrimSpace(kv[1]))
+			if err != nil {
+				parseFailed(key, fmt.Errorf("Environment variable %s contains an invalid integer value: %s", key, entry))
+				return defaultValue
+			}
+			priorities[strings.TrimSpace(kv[0])] = priority
+		}
+
+		keys := make([]string, 0, len(priorities))
+		for k := range priorities {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if priorities[keys[i]] != priorities[keys[j]] {
+				return priorities[keys[i]] < priorities[keys[j]]
+			}
+			return keys[i] < keys[j]
+		})
+		return keys
+	}
+	return defaultValue
+}
+
+// GetEnvZipMap retrieves two parallel delimited variables, e.g. KEYS=a,b,c and
+// VALUES=1,2,3, and zips them into a map. Returns the default if neither
+// variable is set.
+// Panics if only one is set or the lists differ in length.
+func GetEnvZipMap(keyVar, valVar, split string, defaultValue map[string]string) map[string]string {
+	keys := GetEnvArrayString(keyVar, split, nil)
+	values := GetEnvArrayString(valVar, split, nil)
+	if keys == nil && values == nil {
+		return defaultValue
+	}
+	if len(keys) != len(values) {
+		parseFailed(keyVar, fmt.Errorf("Environment variables %s and %s have different lengths: %d and %d", keyVar, valVar, len(keys), len(values)))
+		return defaultValue
+	}
+	result := make(map[string]string, len(keys))
+	for i, k := range keys {
+		result[k] = values[i]
+	}
+	return result
+}
+
+// GetEnvPairs retrieves an environment variable as ordered key-value pairs,
+// e.g. a:1,b:2,a:3. Unlike GetEnvMapStringString it preserves declaration
+// order and keeps duplicate keys.
+// Panics if any entry doesn't contain the key-value delimiter.
+func GetEnvPairs(key, entryDelim, kvDelim string, defaultValue [][2]string) [][2]string {
+	if val := GetEnvString(key, ""); val != "" {
+		entries := strings.Split(val, entryDelim)
+		pairs := make([][2]string, 0, len(entries))
+		for _, entry := range entries {
+			kv := strings.SplitN(entry, kvDelim, 2)
+			if len(kv) != 2 {
+				parseFailed(key, fmt.Errorf("Environment variable %s contains invalid map entry: %s", key, entry))
+				return defaultValue
+			}
+			pairs = append(pairs, [2]string{strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])})
+		}
+		return pairs
+	}
+	return defaultValue
+}
+
+// siPrefixes maps SI prefix symbols to their scale factors.
+var siPrefixes = map[string]float64{
+	"Q": 1e30, "R": 1e27, "Y": 1e24, "Z": 1e21, "E": 1e18, "P": 1e15,
+	"T": 1e12, "G": 1e9, "M": 1e6, "k": 1e3, "h": 1e2,
+	"d": 1e-1, "c": 1e-2, "m": 1e-3, "µ": 1e-6, "u": 1e-6,
+	"n": 1e-9, "p": 1e-12, "f": 1e-15, "a": 1e-18, "z": 1e-21,
+	"y": 1e-24, "r": 1e-27, "q": 1e-30,
+}
+
+// siUnits lists unit symbols that are never split into a prefix and a unit,
+// even when their first letter is also a prefix, e.g. Pa, mol and cd.
+var siUnits = map[string]bool{
+	"m": true, "g": true, "s": true, "A": true, "K": true, "mol": true, "cd": true,
+	"Hz": true, "N": true, "Pa": true, "J": true, "W": true, "C": true, "V": true,
+	"F": true, "Ω": true, "S": true, "Wb": true, "T": true, "H": true, "lm": true,
+	"lx": true, "Bq": true, "Gy": true, "Sv": true, "kat": true, "rad": true,
+	"sr": true, "L": true, "l": true, "t": true, "eV": true, "Da": true,
+	"bar": true, "min": true, "h": true, "d": true, "Wh": true, "VA": true,
+	"dB": true, "B": true, "b": true, "bit": true, "bps": true,
+}
+
+// GetEnvSIValue retrieves an environment variable holding a measurement with
+// an optional SI prefix, e.g. FREQ=2.4GHz or VOLT=3.3mV, and returns the value
+// scaled to the base unit together with that unit (2.4e9, "Hz"). A suffix
+// that is itself a known unit is never split, so 5m is 5 meters and 1mol is
+// one mole; otherwise a leading prefix is stripped, as in 3kPa. The default
+// value is returned with an empty unit if the variable is not set.
+// Panics if the value exists but is not a valid measurement.
+func GetEnvSIValue(key string, defaultValue float64) (value float64, unit string) {
+	if val := GetEnvString(key, ""); val != "" {
+		number, suffix := splitNumber(strings.TrimSpace(val))
+		floatValue, err := strconv.ParseFloat(number, 64)
+		if err != nil {
+			parseFailed(key, fmt.Errorf("Environment variable %s is not a valid measurement: %s", key, val))
+			return defaultValue, ""
+		}
+		suffix = strings.TrimSpace(suffix)
+		if siUnits[suffix] {
+			return floatValue, suffix
+		}
+		for symbol, scale := range siPrefixes {
+			if rest, ok := strings.CutPrefix(suffix, symbol); ok && rest != "" {
+				return floatValue * scale, rest
+			}
+		}
+		return floatValue, suffix
+	}
+	return defaultValue, ""
+}
+
+// splitNumber splits val into a leading decimal number (with optional sign,
+// fraction and exponent) and the remaining suffix.
+func splitNumber(val string) (number, suffix string) {
+	i := 0
+	if i < len(val) && (val[i] == '+' || val[i] == '-') {
+		i++
+	}
+	for i < len(val) && (isDigit(val[i]) || val[i] == '.') {
+		i++
+	}
+	// Only treat e/E as an exponent when digits follow, so 2Em keeps its prefix
+	if i < len(val) && (val[i] == 'e' || val[i] == 'E') {
+		j := i + 1
+		if j < len(val) && (val[j] == '+' || val[j] == '-') {
+			j++
+		}
+		if j < len(val) && isDigit(val[j]) {
+			for j < len(val) && isDigit(val[j]) {
+				j++
+			}
+			i = j
+		}
+	}
+	return val[:i], val[i:]
+}
@@ -1,7 +1,14 @@
 package env
 
 import (
+    "errors"
+    "io"
+    "math"
     "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "sync"
     "testing"
     "time"
 )
@@ -161,4 +168,2194 @@ func TestGetEnvMapStringString(t *testing.T) {
     if gotDef["default"] != "value" {
         t.Errorf("expected default value to be returned, got %v", gotDef)
     }
-}
\ No newline at end of file
+}
+
+// Test for map lookups using configured default delimiters
+func TestGetEnvMap(t *testing.T) {
+    defer Reset()
+    os.Setenv("TEST_MAP_DEFAULT_DELIMS", "a:1,b:2")
+    defer os.Unsetenv("TEST_MAP_DEFAULT_DELIMS")
+
+    if got := GetEnvMap("TEST_MAP_DEFAULT_DELIMS", nil); !reflect.DeepEqual(got, map[string]string{"a": "1", "b": "2"}) {
+        t.Errorf("got %v; want map[a:1 b:2]", got)
+    }
+
+    // Configured delimiters apply to GetEnvMap only
+    SetMapDelimiters(";", "=")
+    os.Setenv("TEST_MAP_DEFAULT_DELIMS", "a=1;b=2")
+    if got := GetEnvMap("TEST_MAP_DEFAULT_DELIMS", nil); !reflect.DeepEqual(got, map[string]string{"a": "1", "b": "2"}) {
+        t.Errorf("got %v; want map[a:1 b:2]", got)
+    }
+    os.Setenv("TEST_MAP_DEFAULT_DELIMS", "a|1,b|2")
+    if got := GetEnvMapStringString("TEST_MAP_DEFAULT_DELIMS", ",", "|", nil); !reflect.DeepEqual(got, map[string]string{"a": "1", "b": "2"}) {
+        t.Errorf("got %v; want map[a:1 b:2]", got)
+    }
+
+    def := map[string]string{"default": "value"}
+    if got := GetEnvMap("TEST_MAP_DEFAULT_DELIMS_MISSING", def); !reflect.DeepEqual(got, def) {
+        t.Errorf("expected default value to be returned, got %v", got)
+    }
+}
+
+// Test for retrieving a map of names to durations
+func TestGetEnvMapStringDuration(t *testing.T) {
+    os.Setenv("TEST_MAP_DURATION", "read:5s, write:10s,idle:1m30s")
+    defer os.Unsetenv("TEST_MAP_DURATION")
+
+    got := GetEnvMapStringDuration("TEST_MAP_DURATION", ",", ":", nil)
+    want := map[string]time.Duration{
+        "read":  5 * time.Second,
+        "write": 10 * time.Second,
+        "idle":  90 * time.Second,
+    }
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("got %v; want %v", got, want)
+    }
+
+    // Test default return
+    os.Unsetenv("TEST_MAP_DURATION")
+    def := map[string]time.Duration{"default": time.Second}
+    if gotDef := GetEnvMapStringDuration("TEST_MAP_DURATION", ",", ":", def); !reflect.DeepEqual(gotDef, def) {
+        t.Errorf("expected default value to be returned, got %v", gotDef)
+    }
+
+    // An invalid duration panics
+    os.Setenv("TEST_MAP_DURATION", "read:5s,write:soon")
+    defer func() {
+        if recover() == nil {
+            t.Errorf("expected panic on invalid duration")
+        }
+    }()
+    GetEnvMapStringDuration("TEST_MAP_DURATION", ",", ":", nil)
+}
+
+// Test for retrieving a map of feature flags
+func TestGetEnvMapStringBool(t *testing.T) {
+    os.Setenv("TEST_MAP_BOOL", "f1:true, f2:false,f3:1")
+    defer os.Unsetenv("TEST_MAP_BOOL")
+
+    got := GetEnvMapStringBool("TEST_MAP_BOOL", ",", ":", nil)
+    want := map[string]bool{"f1": true, "f2": false, "f3": true}
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("got %v; want %v", got, want)
+    }
+
+    // Test default return
+    os.Unsetenv("TEST_MAP_BOOL")
+    def := map[string]bool{"default": true}
+    if gotDef := GetEnvMapStringBool("TEST_MAP_BOOL", ",", ":", def); !reflect.DeepEqual(gotDef, def) {
+        t.Errorf("expected default value to be returned, got %v", gotDef)
+    }
+
+    // An invalid boolean panics, naming the entry
+    os.Setenv("TEST_MAP_BOOL", "f1:true,f2:maybe")
+    defer func() {
+        if r := recover(); r == nil || !strings.Contains(r.(string), "f2:maybe") {
+            t.Errorf("got panic %v; want one naming f2:maybe", r)
+        }
+    }()
+    GetEnvMapStringBool("TEST_MAP_BOOL", ",", ":", nil)
+}
+
+// Test for retrieving a map of weights
+func TestGetEnvMapStringFloat64(t *testing.T) {
+    os.Setenv("TEST_MAP_FLOAT", "a:0.7,b: 0.25")
+    defer os.Unsetenv("TEST_MAP_FLOAT")
+
+    got := GetEnvMapStringFloat64("TEST_MAP_FLOAT", ",", ":", nil)
+    want := map[string]float64{"a": 0.7, "b": 0.25}
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("got %v; want %v", got, want)
+    }
+
+    // Test default return
+    os.Unsetenv("TEST_MAP_FLOAT")
+    def := map[string]float64{"default": 1}
+    if gotDef := GetEnvMapStringFloat64("TEST_MAP_FLOAT", ",", ":", def); !reflect.DeepEqual(gotDef, def) {
+        t.Errorf("expected default value to be returned, got %v", gotDef)
+    }
+
+    // An invalid float panics, naming the entry
+    os.Setenv("TEST_MAP_FLOAT", "a:0.7,b:heavy")
+    defer func() {
+        if r := recover(); r == nil || !strings.Contains(r.(string), "b:heavy") {
+            t.Errorf("got panic %v; want one naming b:heavy", r)
+        }
+    }()
+    GetEnvMapStringFloat64("TEST_MAP_FLOAT", ",", ":", nil)
+}
+
+// Test for retrieving a map keyed by integers
+func TestGetEnvMapIntString(t *testing.T) {
+    os.Setenv("TEST_MAP_INT_KEYS", "404:not found, 500:server error")
+    defer os.Unsetenv("TEST_MAP_INT_KEYS")
+
+    got := GetEnvMapIntString("TEST_MAP_INT_KEYS", ",", ":", nil)
+    want := map[int]string{404: "not found", 500: "server error"}
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("got %v; want %v", got, want)
+    }
+
+    // Test default return
+    os.Unsetenv("TEST_MAP_INT_KEYS")
+    def := map[int]string{200: "ok"}
+    if gotDef := GetEnvMapIntString("TEST_MAP_INT_KEYS", ",", ":", def); !reflect.DeepEqual(gotDef, def) {
+        t.Errorf("expected default value to be returned, got %v", gotDef)
+    }
+
+    // A non-numeric key panics, naming the entry
+    os.Setenv("TEST_MAP_INT_KEYS", "404:not found,teapot:418")
+    defer func() {
+        if r := recover(); r == nil || !strings.Contains(r.(string), "teapot:418") {
+            t.Errorf("got panic %v; want one naming teapot:418", r)
+        }
+    }()
+    GetEnvMapIntString("TEST_MAP_INT_KEYS", ",", ":", nil)
+}
+
+// Test for retrieving all variables whose names match a glob pattern
+func TestGetEnvMatching(t *testing.T) {
+    os.Setenv("TEST_API_TOKEN", "a")
+    os.Setenv("TEST_DB_TOKEN", "b")
+    os.Setenv("FEATURE_X", "on")
+    os.Setenv("FEATURE_XY", "off")
+    defer os.Unsetenv("TEST_API_TOKEN")
+    defer os.Unsetenv("TEST_DB_TOKEN")
+    defer os.Unsetenv("FEATURE_X")
+    defer os.Unsetenv("FEATURE_XY")
+
+    got := GetEnvMatching("TEST_*_TOKEN")
+    if len(got) != 2 || got["TEST_API_TOKEN"] != "a" || got["TEST_DB_TOKEN"] != "b" {
+        t.Errorf("got %v; want TEST_API_TOKEN and TEST_DB_TOKEN", got)
+    }
+
+    // A single-character wildcard must not match longer suffixes
+    got = GetEnvMatching("FEATURE_?")
+    if len(got) != 1 || got["FEATURE_X"] != "on" {
+        t.Errorf("got %v; want only FEATURE_X", got)
+    }
+
+    // A pattern that matches nothing returns an empty map
+    got = GetEnvMatching("NO_SUCH_VARIABLE_*")
+    if len(got) != 0 {
+        t.Errorf("expected empty result, got %v", got)
+    }
+}
+
+// Test for applying a transform to a resolved string variable
+func TestGetEnvStringTransform(t *testing.T) {
+    os.Setenv("TEST_TRANSFORM", "  MiXeD  ")
+    defer os.Unsetenv("TEST_TRANSFORM")
+
+    transform := func(s string) string { return strings.ToLower(strings.TrimSpace(s)) }
+
+    got := GetEnvStringTransform("TEST_TRANSFORM", "default", transform)
+    if got != "mixed" {
+        t.Errorf("got %q; want %q", got, "mixed")
+    }
+
+    // The default value must be returned untouched
+    got = GetEnvStringTransform("TEST_TRANSFORM_MISSING", "  DEFAULT ", transform)
+    if got != "  DEFAULT " {
+        t.Errorf("got %q; want %q", got, "  DEFAULT ")
+    }
+}
+
+// Test for retrieving an integer with underscore digit separators
+func TestGetEnvIntSep(t *testing.T) {
+    os.Setenv("TEST_INT_SEP", "1_000_000")
+    defer os.Unsetenv("TEST_INT_SEP")
+
+    if got := GetEnvIntSep("TEST_INT_SEP", 0); got != 1000000 {
+        t.Errorf("got %d; want %d", got, 1000000)
+    }
+
+    // Plain numbers keep working
+    os.Setenv("TEST_INT_SEP", "42")
+    if got := GetEnvIntSep("TEST_INT_SEP", 0); got != 42 {
+        t.Errorf("got %d; want %d", got, 42)
+    }
+
+    // Misplaced separators must panic
+    for _, val := range []string{"_100", "100_", "1__000", "-_1"} {
+        os.Setenv("TEST_INT_SEP", val)
+        func() {
+            defer func() {
+                if recover() == nil {
+                    t.Errorf("expected panic for %q", val)
+                }
+            }()
+            GetEnvIntSep("TEST_INT_SEP", 0)
+        }()
+    }
+}
+
+// Test for retrieving an unsigned integer without wrapping
+func TestGetEnvUint(t *testing.T) {
+    os.Setenv("TEST_UINT", "42")
+    defer os.Unsetenv("TEST_UINT")
+    if got := GetEnvUint("TEST_UINT", 0); got != 42 {
+        t.Errorf("got %d; want %d", got, 42)
+    }
+
+    if got := GetEnvUint("TEST_UINT_MISSING", 7); got != 7 {
+        t.Errorf("got %d; want %d", got, 7)
+    }
+
+    // Negative and overflowing values must panic
+    for _, val := range []string{"-1", "18446744073709551616"} {
+        os.Setenv("TEST_UINT", val)
+        func() {
+            defer func() {
+                if recover() == nil {
+                    t.Errorf("expected panic for %q", val)
+                }
+            }()
+            GetEnvUint("TEST_UINT", 0)
+        }()
+    }
+}
+
+// Test for retrieving an integer in an explicit base
+func TestGetEnvIntBase(t *testing.T) {
+    defer os.Unsetenv("TEST_INT_BASE")
+
+    tests := []struct {
+        val  string
+        base int
+        want int
+    }{
+        {"FF", 16, 255},
+        {"1010", 2, 10},
+        {"755", 8, 493},
+        {"42", 10, 42},
+        {"-ff", 16, -255},
+    }
+    for _, tt := range tests {
+        os.Setenv("TEST_INT_BASE", tt.val)
+        if got := GetEnvIntBase("TEST_INT_BASE", tt.base, 0); got != tt.want {
+            t.Errorf("GetEnvIntBase(%q, %d) = %d; want %d", tt.val, tt.base, got, tt.want)
+        }
+    }
+
+    // Digits outside the base panic
+    os.Setenv("TEST_INT_BASE", "102")
+    defer func() {
+        if recover() == nil {
+            t.Errorf("expected panic for invalid binary digits")
+        }
+    }()
+    GetEnvIntBase("TEST_INT_BASE", 2, 0)
+}
+
+// Test for inferring the base from a literal prefix
+func TestGetEnvIntAuto(t *testing.T) {
+    defer os.Unsetenv("TEST_INT_AUTO")
+
+    tests := map[string]int{
+        "0xFF":   255,
+        "0b1010": 10,
+        "0o755":  493,
+        "1234":   1234,
+        "-0x10":  -16,
+    }
+    for val, want := range tests {
+        os.Setenv("TEST_INT_AUTO", val)
+        if got := GetEnvIntAuto("TEST_INT_AUTO", 0); got != want {
+            t.Errorf("GetEnvIntAuto(%q) = %d; want %d", val, got, want)
+        }
+    }
+
+    if got := GetEnvIntAuto("TEST_INT_AUTO_MISSING", 7); got != 7 {
+        t.Errorf("got %d; want %d", got, 7)
+    }
+
+    os.Setenv("TEST_INT_AUTO", "0xZZ")
+    defer func() {
+        if recover() == nil {
+            t.Errorf("expected panic for invalid hex literal")
+        }
+    }()
+    GetEnvIntAuto("TEST_INT_AUTO", 0)
+}
+
+// Test for retrieving a duration with day and week units
+func TestGetEnvDurationHuman(t *testing.T) {
+    defer os.Unsetenv("TEST_DURATION_HUMAN")
+
+    cases := map[string]time.Duration{
+        "30d":   30 * 24 * time.Hour,
+        "2w":    14 * 24 * time.Hour,
+        "1h30m": 90 * time.Minute,
+        "1w1d":  8 * 24 * time.Hour,
+    }
+    for val, want := range cases {
+        os.Setenv("TEST_DURATION_HUMAN", val)
+        if got := GetEnvDurationHuman("TEST_DURATION_HUMAN", 0); got != want {
+            t.Errorf("for %q got %v; want %v", val, got, want)
+        }
+    }
+
+    // Totals beyond the range of time.Duration are errors, not wrapped values
+    for _, val := range []string{"20000w", "15000w15000w", "2562047h1w"} {
+        if d, err := parseHumanDuration(val); err == nil {
+            t.Errorf("for %q got %v; want an overflow error", val, d)
+        }
+    }
+    if _, err := parseHumanDuration("106751d23h"); err != nil {
+        t.Errorf("unexpected error near the upper bound: %v", err)
+    }
+
+    // An unknown unit must panic
+    os.Setenv("TEST_DURATION_HUMAN", "3y")
+    defer func() {
+        if recover() == nil {
+            t.Errorf("expected panic for invalid unit")
+        }
+    }()
+    GetEnvDurationHuman("TEST_DURATION_HUMAN", 0)
+}
+
+// Test for retrieving a delimited variable grouped into fixed-size chunks
+func TestGetEnvArrayStringChunks(t *testing.T) {
+    os.Setenv("TEST_CHUNKS", "a,b,c,d")
+    defer os.Unsetenv("TEST_CHUNKS")
+
+    got := GetEnvArrayStringChunks("TEST_CHUNKS", ",", 2, nil)
+    want := [][]string{{"a", "b"}, {"c", "d"}}
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("got %v; want %v", got, want)
+    }
+
+    // The final chunk carries the remainder
+    os.Setenv("TEST_CHUNKS", "a,b,c,d,e")
+    got = GetEnvArrayStringChunks("TEST_CHUNKS", ",", 2, nil)
+    want = [][]string{{"a", "b"}, {"c", "d"}, {"e"}}
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("got %v; want %v", got, want)
+    }
+}
+
+// Test for routing parse failures to a registered error handler
+func TestSetErrorHandler(t *testing.T) {
+    os.Setenv("TEST_ERROR_HANDLER", "not-a-number")
+    defer os.Unsetenv("TEST_ERROR_HANDLER")
+
+    var gotKey string
+    var gotErr error
+    SetErrorHandler(func(key string, err error) {
+        gotKey, gotErr = key, err
+    })
+    defer SetErrorHandler(nil)
+
+    // The getter must fall back to the default instead of panicking
+    if got := GetEnvInt("TEST_ERROR_HANDLER", 7); got != 7 {
+        t.Errorf("got %d; want default %d", got, 7)
+    }
+    if gotKey != "TEST_ERROR_HANDLER" || gotErr == nil {
+        t.Errorf("handler got key %q, err %v; want key TEST_ERROR_HANDLER and an error", gotKey, gotErr)
+    }
+
+    // Without a handler the getter panics again
+    SetErrorHandler(nil)
+    defer func() {
+        if recover() == nil {
+            t.Errorf("expected panic without an error handler")
+        }
+    }()
+    GetEnvInt("TEST_ERROR_HANDLER", 7)
+}
+
+// Test for retrieving a quote-aware delimited list
+func TestGetEnvArrayStringQuoted(t *testing.T) {
+    os.Setenv("TEST_QUOTED", `"a,b",c,'d,e'`)
+    defer os.Unsetenv("TEST_QUOTED")
+
+    got := GetEnvArrayStringQuoted("TEST_QUOTED", ",", nil)
+    want := []string{"a,b", "c", "d,e"}
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("got %v; want %v", got, want)
+    }
+
+    // Unquoted lists behave like GetEnvArrayString
+    os.Setenv("TEST_QUOTED", "x,y,z")
+    got = GetEnvArrayStringQuoted("TEST_QUOTED", ",", nil)
+    want = []string{"x", "y", "z"}
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("got %v; want %v", got, want)
+    }
+
+    // Quotes inside an element are ordinary characters
+    os.Setenv("TEST_QUOTED", `O'Brien,Smith,"D'Arcy, Jr."`)
+    got = GetEnvArrayStringQuoted("TEST_QUOTED", ",", nil)
+    want = []string{"O'Brien", "Smith", "D'Arcy, Jr."}
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("got %v; want %v", got, want)
+    }
+}
+
+// Test for masking values for display
+func TestMaskValue(t *testing.T) {
+    cases := []struct {
+        val    string
+        reveal int
+        mask   rune
+        want   string
+    }{
+        {"secret", 0, '*', "******"},
+        {"secret", 2, '*', "****et"},
+        {"secret", 10, '*', "******"},
+        {"secret", 3, '#', "###ret"},
+        {"пароль", 2, '•', "••••ль"},
+    }
+    for _, c := range cases {
+        if got := MaskValue(c.val, c.reveal, c.mask); got != c.want {
+            t.Errorf("MaskValue(%q, %d, %q) = %q; want %q", c.val, c.reveal, c.mask, got, c.want)
+        }
+    }
+}
+
+// Test for retrieving a masked string environment variable
+func TestGetEnvStringMasked(t *testing.T) {
+    os.Setenv("TEST_MASKED", "supersecret")
+    defer os.Unsetenv("TEST_MASKED")
+
+    if got := GetEnvStringMasked("TEST_MASKED", 4, "default"); got != "*******cret" {
+        t.Errorf("got %q; want %q", got, "*******cret")
+    }
+    if got := GetEnvStringMasked("TEST_MASKED_MISSING", 4, "default"); got != "default" {
+        t.Errorf("got %q; want %q", got, "default")
+    }
+}
+
+// Test for transparently decrypting values with a registered prefix
+func TestRegisterDecryptor(t *testing.T) {
+    RegisterDecryptor("enc:", func(s string) (string, error) {
+        if s == "broken" {
+            return "", errors.New("bad ciphertext")
+        }
+        return strings.ToUpper(s), nil
+    })
+    defer Reset()
+
+    os.Setenv("TEST_DECRYPT", "enc:hunter2")
+    defer os.Unsetenv("TEST_DECRYPT")
+    if got := GetEnvString("TEST_DECRYPT", ""); got != "HUNTER2" {
+        t.Errorf("got %q; want %q", got, "HUNTER2")
+    }
+
+    // Values without the prefix pass through untouched
+    os.Setenv("TEST_DECRYPT", "plain")
+    if got := GetEnvString("TEST_DECRYPT", ""); got != "plain" {
+        t.Errorf("got %q; want %q", got, "plain")
+    }
+
+    // A decryption failure panics
+    os.Setenv("TEST_DECRYPT", "enc:broken")
+    defer func() {
+        if recover() == nil {
+            t.Errorf("expected panic on decrypt failure")
+        }
+    }()
+    GetEnvString("TEST_DECRYPT", "")
+}
+
+// Test for enforcing a registered validator on every read of a key
+func TestRegisterValidator(t *testing.T) {
+    defer Reset()
+    RegisterValidator("TEST_VALIDATED", func(s string) error {
+        if !strings.HasPrefix(s, "https://") {
+            return errors.New("must be an https URL")
+        }
+        return nil
+    })
+
+    os.Setenv("TEST_VALIDATED", "https://example.com")
+    defer os.Unsetenv("TEST_VALIDATED")
+    if got := GetEnvString("TEST_VALIDATED", ""); got != "https://example.com" {
+        t.Errorf("got %q; want %q", got, "https://example.com")
+    }
+
+    // Failures go to the error handler and fall back to the default
+    var reported error
+    SetErrorHandler(func(key string, err error) { reported = err })
+    os.Setenv("TEST_VALIDATED", "http://example.com")
+    if got := GetEnvString("TEST_VALIDATED", "fallback"); got != "fallback" {
+        t.Errorf("got %q; want %q", got, "fallback")
+    }
+    if reported == nil || !strings.Contains(reported.Error(), "must be an https URL") {
+        t.Errorf("got %v; want validation error", reported)
+    }
+
+    // Other getters enforce the validator too
+    SetErrorHandler(nil)
+    defer func() {
+        if recover() == nil {
+            t.Errorf("expected panic on validation failure")
+        }
+    }()
+    GetEnvArrayString("TEST_VALIDATED", ",", nil)
+}
+
+// Test that a registered transform canonicalizes values from every source
+func TestRegisterTransform(t *testing.T) {
+    dir := t.TempDir()
+    defer Load(envDir)
+    defer Reset()
+    RegisterTransform("TEST_REGION", strings.ToUpper)
+
+    // From a loaded file
+    os.WriteFile(filepath.Join(dir, "app.env"), []byte("TEST_REGION=eu-west-1\n"), 0o644)
+    Load(dir)
+    if got := GetEnvString("TEST_REGION", ""); got != "EU-WEST-1" {
+        t.Errorf("got %q; want %q", got, "EU-WEST-1")
+    }
+
+    // From memory
+    SetEnvTransient("TEST_REGION", "us-east-2")
+    if got := GetEnvString("TEST_REGION", ""); got != "US-EAST-2" {
+        t.Errorf("got %q; want %q", got, "US-EAST-2")
+    }
+
+    // From the OS environment
+    os.Setenv("TEST_REGION", "ap-south-1")
+    defer os.Unsetenv("TEST_REGION")
+    if got := GetEnvString("TEST_REGION", ""); got != "AP-SOUTH-1" {
+        t.Errorf("got %q; want %q", got, "AP-SOUTH-1")
+    }
+
+    // Defaults and other keys are left alone
+    if got := GetEnvString("TEST_REGION_MISSING", "local"); got != "local" {
+        t.Errorf("got %q; want %q", got, "local")
+    }
+}
+
+// Test that RawValue returns stored values before any processing
+func TestRawValue(t *testing.T) {
+    dir := t.TempDir()
+    defer Load(envDir)
+    defer Reset()
+    RegisterDecryptor("enc:", func(s string) (string, error) { return "secret", nil })
+    RegisterTransform("TEST_RAW_REGION", strings.ToUpper)
+
+    os.WriteFile(filepath.Join(dir, "app.env"), []byte("TEST_RAW_TOKEN=enc:abc\nTEST_RAW_REGION=eu-west-1\n"), 0o644)
+    Load(dir)
+
+    if got, ok := RawValue("TEST_RAW_TOKEN"); !ok || got != "enc:abc" {
+        t.Errorf("got (%q, %v); want (\"enc:abc\", true)", got, ok)
+    }
+    if got := GetEnvString("TEST_RAW_TOKEN", ""); got != "secret" {
+        t.Errorf("got %q; want %q", got, "secret")
+    }
+    if got, _ := RawValue("TEST_RAW_REGION"); got != "eu-west-1" {
+        t.Errorf("got %q; want %q", got, "eu-west-1")
+    }
+    if got := GetEnvString("TEST_RAW_REGION", ""); got != "EU-WEST-1" {
+        t.Errorf("got %q; want %q", got, "EU-WEST-1")
+    }
+
+    // The OS environment is not consulted
+    os.Setenv("TEST_RAW_OS_ONLY", "value")
+    defer os.Unsetenv("TEST_RAW_OS_ONLY")
+    if _, ok := RawValue("TEST_RAW_OS_ONLY"); ok {
+        t.Errorf("expected OS-only key to be absent")
+    }
+}
+
+// Test for enforcing a minimum length on secrets without leaking them
+func TestGetSecretValidated(t *testing.T) {
+    secret := strings.Repeat("k", 32)
+    os.Setenv("TEST_SECRET_KEY", secret)
+    defer os.Unsetenv("TEST_SECRET_KEY")
+    if got := GetSecretValidated("TEST_SECRET_KEY", 32, ""); got != secret {
+        t.Errorf("got %q; want %q", got, secret)
+    }
+
+    // A short secret panics without revealing the value
+    os.Setenv("TEST_SECRET_KEY", "hunter2")
+    defer func() {
+        r := recover()
+        if r == nil {
+            t.Fatalf("expected panic for a short secret")
+        }
+        if strings.Contains(r.(string), "hunter2") {
+            t.Errorf("panic message %q leaks the secret", r)
+        }
+    }()
+    GetSecretValidated("TEST_SECRET_KEY", 32, "")
+}
+
+// Test for reading a value through an io.Reader
+func TestGetEnvReader(t *testing.T) {
+    os.Setenv("TEST_READER", `{"name":"svc"}`)
+    defer os.Unsetenv("TEST_READER")
+
+    data, err := io.ReadAll(GetEnvReader("TEST_READER", ""))
+    if err != nil || string(data) != `{"name":"svc"}` {
+        t.Errorf("got (%q, %v); want %q", data, err, `{"name":"svc"}`)
+    }
+
+    data, _ = io.ReadAll(GetEnvReader("TEST_READER_MISSING", "default"))
+    if string(data) != "default" {
+        t.Errorf("got %q; want %q", data, "default")
+    }
+}
+
+// Test for inferring the most specific type of a value
+func TestGetEnvInferred(t *testing.T) {
+    defer os.Unsetenv("TEST_INFERRED")
+
+    tests := []struct {
+        val  string
+        want any
+    }{
+        {"42", 42},
+        {"-7", -7},
+        {"1.5", 1.5},
+        {"true", true},
+        {"FALSE", false},
+        {"5s", 5 * time.Second},
+        {"1h30m", 90 * time.Minute},
+        {"1", 1},
+        {"hello", "hello"},
+        {"nan", "nan"},
+        {"Infinity", "Infinity"},
+        {"-inf", "-inf"},
+        {"0x1p4", "0x1p4"},
+        {"1_000", "1_000"},
+        {"1_000.5", "1_000.5"},
+    }
+    for _, tt := range tests {
+        os.Setenv("TEST_INFERRED", tt.val)
+        if got := GetEnvInferred("TEST_INFERRED"); got != tt.want {
+            t.Errorf("GetEnvInferred(%q) = %v (%T); want %v (%T)", tt.val, got, got, tt.want, tt.want)
+        }
+    }
+
+    if got := GetEnvInferred("TEST_INFERRED_MISSING"); got != nil {
+        t.Errorf("got %v; want nil", got)
+    }
+}
+
+// Test for custom boolean words
+func TestSetBoolValues(t *testing.T) {
+    defer Reset()
+    SetBoolValues([]string{"si", "ja"}, []string{"no", "nein"})
+    defer os.Unsetenv("TEST_BOOL_WORDS")
+
+    tests := map[string]bool{"si": true, "SI": true, "ja": true, "no": false, "Nein": false, "true": true, "0": false}
+    for val, want := range tests {
+        os.Setenv("TEST_BOOL_WORDS", val)
+        if got := GetEnvBool("TEST_BOOL_WORDS", !want); got != want {
+            t.Errorf("GetEnvBool(%q) = %v; want %v", val, got, want)
+        }
+    }
+
+    // Unknown words still panic
+    os.Setenv("TEST_BOOL_WORDS", "quizas")
+    defer func() {
+        if recover() == nil {
+            t.Errorf("expected panic for an unknown word")
+        }
+    }()
+    GetEnvBool("TEST_BOOL_WORDS", false)
+}
+
+// Test for retrieving a single element of a delimited variable by index
+func TestGetEnvArrayStringAt(t *testing.T) {
+    os.Setenv("TEST_ARRAY_AT", "host1, host2 ,host3")
+    defer os.Unsetenv("TEST_ARRAY_AT")
+
+    if got := GetEnvArrayStringAt("TEST_ARRAY_AT", ",", 1, "default"); got != "host2" {
+        t.Errorf("got %q; want %q", got, "host2")
+    }
+    if got := GetEnvArrayStringAt("TEST_ARRAY_AT", ",", 3, "default"); got != "default" {
+        t.Errorf("got %q; want %q for out-of-range index", got, "default")
+    }
+    if got := GetEnvArrayStringAt("TEST_ARRAY_AT_MISSING", ",", 0, "default"); got != "default" {
+        t.Errorf("got %q; want %q for unset variable", got, "default")
+    }
+}
+
+// Test for retrieving an array of durations with day and week units
+func TestGetEnvArrayDurationHuman(t *testing.T) {
+    os.Setenv("TEST_ARRAY_DURATION_HUMAN", "12h,1d,2w")
+    defer os.Unsetenv("TEST_ARRAY_DURATION_HUMAN")
+
+    got := GetEnvArrayDurationHuman("TEST_ARRAY_DURATION_HUMAN", ",", nil)
+    want := []time.Duration{12 * time.Hour, 24 * time.Hour, 14 * 24 * time.Hour}
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("got %v; want %v", got, want)
+    }
+
+    // An invalid element must panic
+    os.Setenv("TEST_ARRAY_DURATION_HUMAN", "1d,soon")
+    defer func() {
+        if recover() == nil {
+            t.Errorf("expected panic for invalid element")
+        }
+    }()
+    GetEnvArrayDurationHuman("TEST_ARRAY_DURATION_HUMAN", ",", nil)
+}
+
+// Test for distinguishing absent, empty and set variables
+func TestLookupEnvString(t *testing.T) {
+    if val, ok := LookupEnvString("TEST_LOOKUP_ABSENT"); ok || val != "" {
+        t.Errorf("got (%q, %v); want (\"\", false)", val, ok)
+    }
+
+    os.Setenv("TEST_LOOKUP_EMPTY", "")
+    defer os.Unsetenv("TEST_LOOKUP_EMPTY")
+    if val, ok := LookupEnvString("TEST_LOOKUP_EMPTY"); !ok || val != "" {
+        t.Errorf("got (%q, %v); want (\"\", true)", val, ok)
+    }
+
+    // Explicitly empty values from *.env files count as set too
+    SetEnvTransient("TEST_LOOKUP_FILE_EMPTY", "")
+    defer Load(envDir)
+    if val, ok := LookupEnvString("TEST_LOOKUP_FILE_EMPTY"); !ok || val != "" {
+        t.Errorf("got (%q, %v); want (\"\", true)", val, ok)
+    }
+
+    os.Setenv("TEST_LOOKUP_SET", "value")
+    defer os.Unsetenv("TEST_LOOKUP_SET")
+    if val, ok := LookupEnvString("TEST_LOOKUP_SET"); !ok || val != "value" {
+        t.Errorf("got (%q, %v); want (\"value\", true)", val, ok)
+    }
+}
+
+// Test for joining several variables into one value
+func TestGetEnvStringJoin(t *testing.T) {
+    os.Setenv("TEST_JOIN_FIRST", "Ada")
+    os.Setenv("TEST_JOIN_LAST", "Lovelace")
+    defer os.Unsetenv("TEST_JOIN_FIRST")
+    defer os.Unsetenv("TEST_JOIN_LAST")
+
+    got := GetEnvStringJoin([]string{"TEST_JOIN_FIRST", "TEST_JOIN_LAST"}, " ", "anonymous")
+    if got != "Ada Lovelace" {
+        t.Errorf("got %q; want %q", got, "Ada Lovelace")
+    }
+
+    // Absent keys are skipped
+    got = GetEnvStringJoin([]string{"TEST_JOIN_FIRST", "TEST_JOIN_MIDDLE", "TEST_JOIN_LAST"}, " ", "anonymous")
+    if got != "Ada Lovelace" {
+        t.Errorf("got %q; want %q", got, "Ada Lovelace")
+    }
+
+    // The default is used when no key resolves
+    got = GetEnvStringJoin([]string{"TEST_JOIN_MIDDLE", "TEST_JOIN_SUFFIX"}, " ", "anonymous")
+    if got != "anonymous" {
+        t.Errorf("got %q; want %q", got, "anonymous")
+    }
+}
+
+// Test that persistent values survive a reload while file values refresh
+func TestReloadKeepsPersistentValues(t *testing.T) {
+    dir := t.TempDir()
+    defer Load(envDir)
+    defer func() { persistentEnv = make(map[string]string) }()
+
+    file := filepath.Join(dir, "app.env")
+    os.WriteFile(file, []byte("TEST_RELOAD_FILE=v1\n"), 0o644)
+    if _, err := Load(dir); err != nil {
+        t.Fatalf("Load failed: %v", err)
+    }
+
+    SetEnvPersistent("TEST_RELOAD_PERSISTENT", "kept")
+    SetEnvTransient("TEST_RELOAD_TRANSIENT", "dropped")
+
+    os.WriteFile(file, []byte("TEST_RELOAD_FILE=v2\n"), 0o644)
+    if _, err := Reload(); err != nil {
+        t.Fatalf("Reload failed: %v", err)
+    }
+
+    if got := GetEnvString("TEST_RELOAD_FILE", ""); got != "v2" {
+        t.Errorf("got %q; want refreshed file value %q", got, "v2")
+    }
+    if got := GetEnvString("TEST_RELOAD_PERSISTENT", ""); got != "kept" {
+        t.Errorf("got %q; want persistent value %q", got, "kept")
+    }
+    if _, ok := LookupEnvString("TEST_RELOAD_TRANSIENT"); ok {
+        t.Errorf("expected transient value to be dropped by reload")
+    }
+}
+
+// Test that concurrent reads see consistent values while Reload swaps the map
+// (run with -race)
+func TestReloadConcurrentReads(t *testing.T) {
+    dir := t.TempDir()
+    defer Load(envDir)
+
+    file := filepath.Join(dir, "app.env")
+    os.WriteFile(file, []byte("TEST_RACE_A=1\nTEST_RACE_B=1\n"), 0o644)
+    if _, err := Load(dir); err != nil {
+        t.Fatalf("Load failed: %v", err)
+    }
+
+    done := make(chan struct{})
+    var wg sync.WaitGroup
+    for i := 0; i < 4; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for {
+                select {
+                case <-done:
+                    return
+                default:
+                }
+                if got := GetEnvString("TEST_RACE_A", ""); got != "1" && got != "2" {
+                    t.Errorf("got %q; want 1 or 2", got)
+                    return
+                }
+                // Both keys come from the same snapshot
+                snapshot := loadedEnv()
+                if snapshot["TEST_RACE_A"] != snapshot["TEST_RACE_B"] {
+                    t.Errorf("got inconsistent snapshot %q/%q", snapshot["TEST_RACE_A"], snapshot["TEST_RACE_B"])
+                    return
+                }
+            }
+        }()
+    }
+
+    for i := 0; i < 50; i++ {
+        val := []string{"1", "2"}[i%2]
+        os.WriteFile(file, []byte("TEST_RACE_A="+val+"\nTEST_RACE_B="+val+"\n"), 0o644)
+        if _, err := Reload(); err != nil {
+            t.Errorf("Reload failed: %v", err)
+        }
+        SetEnvTransient("TEST_RACE_C", val)
+    }
+    close(done)
+    wg.Wait()
+}
+
+// Test that command-line overrides take precedence over the OS environment
+func TestApplyOverrides(t *testing.T) {
+    defer overrideEnv.Store(nil)
+
+    os.Setenv("TEST_OVERRIDE", "from-os")
+    defer os.Unsetenv("TEST_OVERRIDE")
+    if err := ApplyOverrides([]string{"TEST_OVERRIDE=from-cli", "TEST_OVERRIDE_NEW=a=b"}); err != nil {
+        t.Fatalf("ApplyOverrides failed: %v", err)
+    }
+    if got := GetEnvString("TEST_OVERRIDE", ""); got != "from-cli" {
+        t.Errorf("got %q; want %q", got, "from-cli")
+    }
+    if got := GetEnvString("TEST_OVERRIDE_NEW", ""); got != "a=b" {
+        t.Errorf("got %q; want %q", got, "a=b")
+    }
+
+    // Overrides survive a reload
+    Reload()
+    if got := GetEnvString("TEST_OVERRIDE", ""); got != "from-cli" {
+        t.Errorf("got %q after reload; want %q", got, "from-cli")
+    }
+
+    // A malformed pair is rejected and nothing is applied
+    for _, pair := range []string{"NOVALUE", "=value"} {
+        if err := ApplyOverrides([]string{"TEST_OVERRIDE=changed", pair}); err == nil {
+            t.Errorf("expected an error for %q", pair)
+        }
+    }
+    if got := GetEnvString("TEST_OVERRIDE", ""); got != "from-cli" {
+        t.Errorf("got %q; want unchanged %q", got, "from-cli")
+    }
+}
+
+// Test that OnLoad hooks can derive values visible to getters
+func TestOnLoad(t *testing.T) {
+    dir := t.TempDir()
+    defer Load(envDir)
+    defer Reset()
+
+    calls := 0
+    OnLoad(func() {
+        calls++
+        host := GetEnvString("TEST_HOOK_HOST", "")
+        port := GetEnvString("TEST_HOOK_PORT", "")
+        SetEnvTransient("TEST_HOOK_ADDR", host+":"+port)
+    })
+
+    file := filepath.Join(dir, "app.env")
+    os.WriteFile(file, []byte("TEST_HOOK_HOST=db.local\nTEST_HOOK_PORT=5432\n"), 0o644)
+    Load(dir)
+    if got := GetEnvString("TEST_HOOK_ADDR", ""); got != "db.local:5432" {
+        t.Errorf("got %q; want %q", got, "db.local:5432")
+    }
+
+    // Derived values are recomputed on reload
+    os.WriteFile(file, []byte("TEST_HOOK_HOST=db.prod\nTEST_HOOK_PORT=6432\n"), 0o644)
+    Reload()
+    if got := GetEnvString("TEST_HOOK_ADDR", ""); got != "db.prod:6432" {
+        t.Errorf("got %q; want %q", got, "db.prod:6432")
+    }
+    if calls != 2 {
+        t.Errorf("got %d calls; want 2", calls)
+    }
+}
+
+// Test for resolving short names under a namespace prefix
+func TestSetPrefix(t *testing.T) {
+    dir := t.TempDir()
+    defer Load(envDir)
+    defer Reset()
+
+    os.Setenv("TEST_MYAPP_PORT", "8080")
+    defer os.Unsetenv("TEST_MYAPP_PORT")
+    os.WriteFile(filepath.Join(dir, "app.env"), []byte("TEST_MYAPP_HOST=file.local\n"), 0o644)
+    Load(dir)
+
+    // Without a prefix, short names do not resolve but full names do
+    if got := GetEnvString("PORT", "none"); got != "none" {
+        t.Errorf("got %q; want %q", got, "none")
+    }
+    if got := GetEnvString("TEST_MYAPP_PORT", ""); got != "8080" {
+        t.Errorf("got %q; want %q", got, "8080")
+    }
+
+    SetPrefix("TEST_MYAPP_")
+    if got := GetEnvInt("PORT", 0); got != 8080 {
+        t.Errorf("got %d; want %d", got, 8080)
+    }
+    if got := GetEnvString("HOST", ""); got != "file.local" {
+        t.Errorf("got %q; want %q", got, "file.local")
+    }
+
+    // Names without a prefixed variable fall back to the name as given
+    if got := GetEnvString("TEST_MYAPP_PORT", ""); got != "8080" {
+        t.Errorf("got %q; want %q", got, "8080")
+    }
+}
+
+// Test for falling back to an alias when the primary key is absent
+func TestAddAlias(t *testing.T) {
+    defer Reset()
+    AddAlias("TEST_DB_URL", "TEST_DATABASE_URL")
+
+    if got := GetEnvString("TEST_DB_URL", "default"); got != "default" {
+        t.Errorf("got %q; want %q", got, "default")
+    }
+
+    os.Setenv("TEST_DATABASE_URL", "postgres://alias")
+    defer os.Unsetenv("TEST_DATABASE_URL")
+    if got := GetEnvString("TEST_DB_URL", ""); got != "postgres://alias" {
+        t.Errorf("got %q; want %q", got, "postgres://alias")
+    }
+
+    // The primary key takes precedence over its alias
+    os.Setenv("TEST_DB_URL", "postgres://primary")
+    defer os.Unsetenv("TEST_DB_URL")
+    if got := GetEnvString("TEST_DB_URL", ""); got != "postgres://primary" {
+        t.Errorf("got %q; want %q", got, "postgres://primary")
+    }
+}
+
+// Test for canonicalizing lowercase file keys
+func TestSetNormalizeKeys(t *testing.T) {
+    dir := t.TempDir()
+    defer Load(envDir)
+    defer Reset()
+
+    os.WriteFile(filepath.Join(dir, "app.env"), []byte("test_normalize_host=db.local\nTest_Normalize_Port=5432\n"), 0o644)
+    SetNormalizeKeys(strings.ToUpper)
+    Load(dir)
+
+    if got := GetEnvString("TEST_NORMALIZE_HOST", ""); got != "db.local" {
+        t.Errorf("got %q; want %q", got, "db.local")
+    }
+    if got := GetEnvInt("TEST_NORMALIZE_PORT", 0); got != 5432 {
+        t.Errorf("got %d; want %d", got, 5432)
+    }
+
+    // Lookups are normalized too
+    if got := GetEnvString("test_normalize_host", ""); got != "db.local" {
+        t.Errorf("got %q; want %q", got, "db.local")
+    }
+
+    // Without normalization the raw keys are kept
+    Reset()
+    Load(dir)
+    if got := GetEnvString("TEST_NORMALIZE_HOST", "none"); got != "none" {
+        t.Errorf("got %q; want %q", got, "none")
+    }
+}
+
+// Test for lazily iterating over a delimited variable
+func TestRangeEnvArrayString(t *testing.T) {
+    os.Setenv("TEST_RANGE", "a,b,c,d")
+    defer os.Unsetenv("TEST_RANGE")
+
+    var indices []int
+    var values []string
+    RangeEnvArrayString("TEST_RANGE", ",", func(i int, v string) bool {
+        indices = append(indices, i)
+        values = append(values, v)
+        return v != "b"
+    })
+
+    // Iteration must stop after the callback returns false
+    if !reflect.DeepEqual(indices, []int{0, 1}) || !reflect.DeepEqual(values, []string{"a", "b"}) {
+        t.Errorf("got indices %v values %v; want [0 1] [a b]", indices, values)
+    }
+
+    // An unset variable yields no elements
+    RangeEnvArrayString("TEST_RANGE_MISSING", ",", func(i int, v string) bool {
+        t.Errorf("unexpected element %d: %q", i, v)
+        return true
+    })
+}
+
+// Test for falling back to a case-insensitive key match
+func TestGetEnvStringFold(t *testing.T) {
+    os.Setenv("Test_Fold_Key", "folded")
+    defer os.Unsetenv("Test_Fold_Key")
+
+    if got := GetEnvStringFold("TEST_FOLD_KEY", "default"); got != "folded" {
+        t.Errorf("got %q; want %q", got, "folded")
+    }
+
+    // An exact match takes precedence over folded ones
+    os.Setenv("TEST_FOLD_KEY", "exact")
+    defer os.Unsetenv("TEST_FOLD_KEY")
+    if got := GetEnvStringFold("TEST_FOLD_KEY", "default"); got != "exact" {
+        t.Errorf("got %q; want %q", got, "exact")
+    }
+
+    if got := GetEnvStringFold("TEST_FOLD_MISSING", "default"); got != "default" {
+        t.Errorf("got %q; want %q", got, "default")
+    }
+}
+
+// Test for validating a string variable with a custom predicate
+func TestGetEnvStringValid(t *testing.T) {
+    os.Setenv("TEST_VALID", "eu-west-1")
+    defer os.Unsetenv("TEST_VALID")
+
+    valid := func(s string) error {
+        if !strings.HasPrefix(s, "eu-") {
+            return errors.New("region must be in the EU")
+        }
+        return nil
+    }
+
+    if got := GetEnvStringValid("TEST_VALID", "eu-central-1", valid); got != "eu-west-1" {
+        t.Errorf("got %q; want %q", got, "eu-west-1")
+    }
+
+    // A rejected value panics with the validator's error
+    os.Setenv("TEST_VALID", "us-east-1")
+    defer func() {
+        r := recover()
+        if r == nil || !strings.Contains(r.(string), "region must be in the EU") {
+            t.Errorf("expected panic with validator error, got %v", r)
+        }
+    }()
+    GetEnvStringValid("TEST_VALID", "eu-central-1", valid)
+}
+
+// Test for retrieving list elements with per-element attributes
+func TestGetEnvArrayAttributed(t *testing.T) {
+    os.Setenv("TEST_ATTRIBUTED", "host1|weight=5|zone=a,host2|weight=3,host3")
+    defer os.Unsetenv("TEST_ATTRIBUTED")
+
+    got := GetEnvArrayAttributed("TEST_ATTRIBUTED", ",", "|", "=", nil)
+    want := []AttributedValue{
+        {Value: "host1", Attrs: map[string]string{"weight": "5", "zone": "a"}},
+        {Value: "host2", Attrs: map[string]string{"weight": "3"}},
+        {Value: "host3", Attrs: map[string]string{}},
+    }
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("got %v; want %v", got, want)
+    }
+
+    // An attribute without a key-value delimiter panics
+    os.Setenv("TEST_ATTRIBUTED", "host1|weight")
+    defer func() {
+        if recover() == nil {
+            t.Errorf("expected panic for malformed attribute")
+        }
+    }()
+    GetEnvArrayAttributed("TEST_ATTRIBUTED", ",", "|", "=", nil)
+}
+
+// Test for retrieving a boolean that only accepts true or false
+func TestGetEnvBoolStrict(t *testing.T) {
+    os.Setenv("TEST_BOOL_STRICT", "TRUE")
+    defer os.Unsetenv("TEST_BOOL_STRICT")
+
+    if got := GetEnvBoolStrict("TEST_BOOL_STRICT", false); got != true {
+        t.Errorf("got %v; want %v", got, true)
+    }
+
+    // Shorthand and invalid values must panic
+    for _, val := range []string{"1", "yes"} {
+        os.Setenv("TEST_BOOL_STRICT", val)
+        func() {
+            defer func() {
+                if recover() == nil {
+                    t.Errorf("expected panic for %q", val)
+                }
+            }()
+            GetEnvBoolStrict("TEST_BOOL_STRICT", false)
+        }()
+    }
+}
+
+// Test for mapping list elements to their positions
+func TestGetEnvIndexMap(t *testing.T) {
+    os.Setenv("TEST_INDEX_MAP", "high,medium,low,medium")
+    defer os.Unsetenv("TEST_INDEX_MAP")
+
+    got := GetEnvIndexMap("TEST_INDEX_MAP", ",", nil)
+
+    // Duplicates keep the index of their first occurrence
+    want := map[string]int{"high": 0, "medium": 1, "low": 2}
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("got %v; want %v", got, want)
+    }
+}
+
+// Test for injecting a custom OS lookup function
+func TestSetLookupEnv(t *testing.T) {
+    fake := map[string]string{"TEST_FAKE_PORT": "9090", "TEST_FAKE_DEBUG": "true"}
+    SetLookupEnv(func(key string) (string, bool) {
+        val, ok := fake[key]
+        return val, ok
+    })
+    defer Reset()
+
+    if got := GetEnvInt("TEST_FAKE_PORT", 0); got != 9090 {
+        t.Errorf("got %d; want %d", got, 9090)
+    }
+    if got := GetEnvBool("TEST_FAKE_DEBUG", false); got != true {
+        t.Errorf("got %v; want %v", got, true)
+    }
+
+    // Real process variables are invisible to the fake lookup
+    os.Setenv("TEST_REAL_ONLY", "real")
+    defer os.Unsetenv("TEST_REAL_ONLY")
+    if got := GetEnvString("TEST_REAL_ONLY", "default"); got != "default" {
+        t.Errorf("got %q; want %q", got, "default")
+    }
+
+    // Reset restores the real OS lookup
+    Reset()
+    if got := GetEnvString("TEST_REAL_ONLY", "default"); got != "real" {
+        t.Errorf("got %q after Reset; want %q", got, "real")
+    }
+}
+
+// Test for replacing the enumeration of the OS environment
+func TestSetEnviron(t *testing.T) {
+    defer Reset()
+    fake := map[string]string{"TEST_FAKE_ENV_A": "1", "TEST_FAKE_ENV_B": "2", "TEST_FAKE_SRV_0_HOST": "h0"}
+    SetLookupEnv(func(key string) (string, bool) {
+        val, ok := fake[key]
+        return val, ok
+    })
+    SetEnviron(func() []string {
+        var pairs []string
+        for key, val := range fake {
+            pairs = append(pairs, key+"="+val)
+        }
+        return pairs
+    })
+    os.Setenv("TEST_FAKE_ENV_REAL", "real")
+    defer os.Unsetenv("TEST_FAKE_ENV_REAL")
+
+    // Enumerating functions see the fake environment only
+    want := map[string]string{"TEST_FAKE_ENV_A": "1", "TEST_FAKE_ENV_B": "2"}
+    if got := GetEnvMatching("TEST_FAKE_ENV_*"); !reflect.DeepEqual(got, want) {
+        t.Errorf("got %v; want %v", got, want)
+    }
+    if got := GetEnvStringFold("test_fake_env_a", ""); got != "1" {
+        t.Errorf("got %q; want %q", got, "1")
+    }
+    var cfg struct {
+        Servers []struct {
+            Host string `env:"HOST"`
+        } `env:"TEST_FAKE_SRV"`
+    }
+    if err := Unmarshal(&cfg); err != nil || len(cfg.Servers) != 1 || cfg.Servers[0].Host != "h0" {
+        t.Errorf("got (%+v, %v); want one server h0", cfg.Servers, err)
+    }
+
+    // Reset restores os.Environ
+    Reset()
+    if got := GetEnvMatching("TEST_FAKE_ENV_*"); !reflect.DeepEqual(got, map[string]string{"TEST_FAKE_ENV_REAL": "real"}) {
+        t.Errorf("got %v after Reset; want only the real variable", got)
+    }
+}
+
+// Test for expanding a file pattern held in an environment variable
+func TestGetEnvGlob(t *testing.T) {
+    dir := t.TempDir()
+    for _, name := range []string{"a.yaml", "b.yaml", "c.json"} {
+        os.WriteFile(filepath.Join(dir, name), nil, 0o644)
+    }
+
+    os.Setenv("TEST_GLOB", filepath.Join(dir, "*.yaml"))
+    defer os.Unsetenv("TEST_GLOB")
+
+    got := GetEnvGlob("TEST_GLOB", nil)
+    want := []string{filepath.Join(dir, "a.yaml"), filepath.Join(dir, "b.yaml")}
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("got %v; want %v", got, want)
+    }
+
+    // A pattern without matches yields an empty slice
+    os.Setenv("TEST_GLOB", filepath.Join(dir, "*.toml"))
+    if got := GetEnvGlob("TEST_GLOB", nil); got == nil || len(got) != 0 {
+        t.Errorf("got %v; want empty slice", got)
+    }
+
+    // A malformed pattern panics
+    os.Setenv("TEST_GLOB", filepath.Join(dir, "[.yaml"))
+    defer func() {
+        if recover() == nil {
+            t.Errorf("expected panic for malformed pattern")
+        }
+    }()
+    GetEnvGlob("TEST_GLOB", nil)
+}
+
+// Test for retrieving a duration with randomized jitter
+func TestGetEnvDurationJitter(t *testing.T) {
+    os.Setenv("TEST_JITTER", "1s")
+    defer os.Unsetenv("TEST_JITTER")
+
+    SeedJitter(42)
+    defer Reset()
+
+    for i := 0; i < 100; i++ {
+        got := GetEnvDurationJitter("TEST_JITTER", 0, 0.1)
+        if got < 900*time.Millisecond || got > 1100*time.Millisecond {
+            t.Fatalf("got %v; want value within [900ms, 1.1s]", got)
+        }
+    }
+
+    // The same seed yields the same sequence
+    SeedJitter(7)
+    first := GetEnvDurationJitter("TEST_JITTER", 0, 0.5)
+    SeedJitter(7)
+    if second := GetEnvDurationJitter("TEST_JITTER", 0, 0.5); first != second {
+        t.Errorf("got %v and %v; want identical values for the same seed", first, second)
+    }
+
+    // A zero fraction disables jitter
+    if got := GetEnvDurationJitter("TEST_JITTER", 0, 0); got != time.Second {
+        t.Errorf("got %v; want %v", got, time.Second)
+    }
+}
+
+// Test for ordering map keys by their integer values
+func TestGetEnvSortedByValueInt(t *testing.T) {
+    os.Setenv("TEST_SORTED", "b:2,a:1,c:3")
+    defer os.Unsetenv("TEST_SORTED")
+
+    got := GetEnvSortedByValueInt("TEST_SORTED", ",", ":", nil)
+    if want := []string{"a", "b", "c"}; !reflect.DeepEqual(got, want) {
+        t.Errorf("got %v; want %v", got, want)
+    }
+
+    // A non-numeric value panics
+    os.Setenv("TEST_SORTED", "b:2,a:first")
+    defer func() {
+        if recover() == nil {
+            t.Errorf("expected panic for non-numeric value")
+        }
+    }()
+    GetEnvSortedByValueInt("TEST_SORTED", ",", ":", nil)
+}
+
+// Test for a variable that is required only under a condition
+func TestGetEnvStringRequiredIf(t *testing.T) {
+    defer os.Unsetenv("TEST_STORAGE")
+    defer os.Unsetenv("TEST_S3_BUCKET")
+
+    // Condition unmet, key absent: the default is used
+    os.Setenv("TEST_STORAGE", "local")
+    got, err := GetEnvStringRequiredIf("TEST_S3_BUCKET", "TEST_STORAGE", "s3", "none")
+    if err != nil || got != "none" {
+        t.Errorf("got (%q, %v); want (\"none\", nil)", got, err)
+    }
+
+    // Condition met, key absent: an error is returned
+    os.Setenv("TEST_STORAGE", "s3")
+    if _, err := GetEnvStringRequiredIf("TEST_S3_BUCKET", "TEST_STORAGE", "s3", "none"); err == nil {
+        t.Errorf("expected error when the condition is met and the key is absent")
+    }
+
+    // Condition met, key present: the value is returned
+    os.Setenv("TEST_S3_BUCKET", "assets")
+    got, err = GetEnvStringRequiredIf("TEST_S3_BUCKET", "TEST_STORAGE", "s3", "none")
+    if err != nil || got != "assets" {
+        t.Errorf("got (%q, %v); want (\"assets\", nil)", got, err)
+    }
+
+    // Condition unmet, key present: the value is returned
+    os.Setenv("TEST_STORAGE", "local")
+    got, err = GetEnvStringRequiredIf("TEST_S3_BUCKET", "TEST_STORAGE", "s3", "none")
+    if err != nil || got != "assets" {
+        t.Errorf("got (%q, %v); want (\"assets\", nil)", got, err)
+    }
+}
+
+// Test for zipping two parallel lists into a map
+func TestGetEnvZipMap(t *testing.T) {
+    os.Setenv("TEST_ZIP_KEYS", "a,b,c")
+    os.Setenv("TEST_ZIP_VALUES", "1,2,3")
+    defer os.Unsetenv("TEST_ZIP_KEYS")
+    defer os.Unsetenv("TEST_ZIP_VALUES")
+
+    got := GetEnvZipMap("TEST_ZIP_KEYS", "TEST_ZIP_VALUES", ",", nil)
+    if want := map[string]string{"a": "1", "b": "2", "c": "3"}; !reflect.DeepEqual(got, want) {
+        t.Errorf("got %v; want %v", got, want)
+    }
+
+    // Neither list set: the default is returned
+    def := map[string]string{"default": "value"}
+    if got := GetEnvZipMap("TEST_ZIP_NO_KEYS", "TEST_ZIP_NO_VALUES", ",", def); !reflect.DeepEqual(got, def) {
+        t.Errorf("got %v; want %v", got, def)
+    }
+
+    // Lists of different lengths panic
+    os.Setenv("TEST_ZIP_VALUES", "1,2")
+    defer func() {
+        if recover() == nil {
+            t.Errorf("expected panic for unequal lengths")
+        }
+    }()
+    GetEnvZipMap("TEST_ZIP_KEYS", "TEST_ZIP_VALUES", ",", nil)
+}
+
+// Test for resolving profile-specific overrides
+func TestGetEnvStringProfiled(t *testing.T) {
+    os.Setenv("TEST_PROFILED_PORT", "8080")
+    os.Setenv("TEST_PROFILED_PORT.prod", "80")
+    defer os.Unsetenv("TEST_PROFILED_PORT")
+    defer os.Unsetenv("TEST_PROFILED_PORT.prod")
+    defer Reset()
+
+    // Profiled override present
+    SetProfile("prod")
+    if got := GetEnvStringProfiled("TEST_PROFILED_PORT", "0"); got != "80" {
+        t.Errorf("got %q; want %q", got, "80")
+    }
+
+    // Profiled override absent: the base key is used
+    SetProfile("dev")
+    if got := GetEnvStringProfiled("TEST_PROFILED_PORT", "0"); got != "8080" {
+        t.Errorf("got %q; want %q", got, "8080")
+    }
+}
+
+// Test for truncating a list to a limit read from another variable
+func TestGetEnvArrayStringLimited(t *testing.T) {
+    os.Setenv("TEST_WORKERS_LIST", "w1,w2,w3,w4")
+    defer os.Unsetenv("TEST_WORKERS_LIST")
+
+    // The default limit applies when the limit variable is unset
+    got := GetEnvArrayStringLimited("TEST_WORKERS_LIST", ",", "TEST_WORKERS_MAX", 3, nil)
+    if want := []string{"w1", "w2", "w3"}; !reflect.DeepEqual(got, want) {
+        t.Errorf("got %v; want %v", got, want)
+    }
+
+    // The configured limit takes precedence
+    os.Setenv("TEST_WORKERS_MAX", "2")
+    defer os.Unsetenv("TEST_WORKERS_MAX")
+    got = GetEnvArrayStringLimited("TEST_WORKERS_LIST", ",", "TEST_WORKERS_MAX", 3, nil)
+    if want := []string{"w1", "w2"}; !reflect.DeepEqual(got, want) {
+        t.Errorf("got %v; want %v", got, want)
+    }
+}
+
+// Test for parsing an integer array with typed element errors
+func TestLookupEnvArrayInt(t *testing.T) {
+    os.Setenv("TEST_LOOKUP_ARRAY_INT", "1,2,3")
+    defer os.Unsetenv("TEST_LOOKUP_ARRAY_INT")
+
+    got, err := LookupEnvArrayInt("TEST_LOOKUP_ARRAY_INT", ",")
+    if err != nil || !reflect.DeepEqual(got, []int{1, 2, 3}) {
+        t.Errorf("got (%v, %v); want ([1 2 3], nil)", got, err)
+    }
+
+    // The error identifies the offending element
+    os.Setenv("TEST_LOOKUP_ARRAY_INT", "1,2,x3")
+    _, err = LookupEnvArrayInt("TEST_LOOKUP_ARRAY_INT", ",")
+    var elemErr *ElementError
+    if !errors.As(err, &elemErr) {
+        t.Fatalf("got %v; want *ElementError", err)
+    }
+    if elemErr.Index != 2 || elemErr.Value != "x3" || elemErr.Err == nil {
+        t.Errorf("got index %d value %q err %v; want index 2 value \"x3\"", elemErr.Index, elemErr.Value, elemErr.Err)
+    }
+}
+
+// Test for retrieving ordered key-value pairs
+func TestGetEnvPairs(t *testing.T) {
+    os.Setenv("TEST_PAIRS", "z:1,a:2,z:3")
+    defer os.Unsetenv("TEST_PAIRS")
+
+    // Order is preserved and duplicate keys are retained
+    got := GetEnvPairs("TEST_PAIRS", ",", ":", nil)
+    want := [][2]string{{"z", "1"}, {"a", "2"}, {"z", "3"}}
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("got %v; want %v", got, want)
+    }
+}
+
+// Test for retrieving a tri-state boolean
+func TestGetEnvBoolPtr(t *testing.T) {
+    if got := GetEnvBoolPtr("TEST_BOOL_PTR"); got != nil {
+        t.Errorf("got %v; want nil for absent variable", *got)
+    }
+
+    os.Setenv("TEST_BOOL_PTR", "true")
+    defer os.Unsetenv("TEST_BOOL_PTR")
+    if got := GetEnvBoolPtr("TEST_BOOL_PTR"); got == nil || *got != true {
+        t.Errorf("got %v; want pointer to true", got)
+    }
+
+    // An invalid value panics
+    os.Setenv("TEST_BOOL_PTR", "maybe")
+    defer func() {
+        if recover() == nil {
+            t.Errorf("expected panic for invalid boolean")
+        }
+    }()
+    GetEnvBoolPtr("TEST_BOOL_PTR")
+}
+
+// Test for retrieving optional scalar values as pointers
+func TestGetEnvScalarPtrs(t *testing.T) {
+    // Absent variables yield nil pointers
+    if got := GetEnvStringPtr("TEST_PTR_STRING"); got != nil {
+        t.Errorf("got %q; want nil string", *got)
+    }
+    if got := GetEnvIntPtr("TEST_PTR_INT"); got != nil {
+        t.Errorf("got %d; want nil int", *got)
+    }
+    if got := GetEnvFloat64Ptr("TEST_PTR_FLOAT"); got != nil {
+        t.Errorf("got %f; want nil float64", *got)
+    }
+    if got := GetEnvDurationPtr("TEST_PTR_DURATION"); got != nil {
+        t.Errorf("got %v; want nil duration", *got)
+    }
+
+    os.Setenv("TEST_PTR_STRING", "")
+    os.Setenv("TEST_PTR_INT", "0")
+    os.Setenv("TEST_PTR_FLOAT", "1.5")
+    os.Setenv("TEST_PTR_DURATION", "2s")
+    defer os.Unsetenv("TEST_PTR_STRING")
+    defer os.Unsetenv("TEST_PTR_INT")
+    defer os.Unsetenv("TEST_PTR_FLOAT")
+    defer os.Unsetenv("TEST_PTR_DURATION")
+
+    // Present variables yield pointers, even for zero values
+    if got := GetEnvStringPtr("TEST_PTR_STRING"); got == nil || *got != "" {
+        t.Errorf("got %v; want pointer to empty string", got)
+    }
+    if got := GetEnvIntPtr("TEST_PTR_INT"); got == nil || *got != 0 {
+        t.Errorf("got %v; want pointer to 0", got)
+    }
+    if got := GetEnvFloat64Ptr("TEST_PTR_FLOAT"); got == nil || *got != 1.5 {
+        t.Errorf("got %v; want pointer to 1.5", got)
+    }
+    if got := GetEnvDurationPtr("TEST_PTR_DURATION"); got == nil || *got != 2*time.Second {
+        t.Errorf("got %v; want pointer to 2s", got)
+    }
+}
+
+// Test for resolving a variable named by another variable
+func TestGetEnvIndirect(t *testing.T) {
+    os.Setenv("TEST_ACTIVE_PROFILE_VAR", "TEST_PROFILE_PROD")
+    os.Setenv("TEST_PROFILE_PROD", "prod-settings")
+    defer os.Unsetenv("TEST_ACTIVE_PROFILE_VAR")
+    defer os.Unsetenv("TEST_PROFILE_PROD")
+
+    if got := GetEnvIndirect("TEST_ACTIVE_PROFILE_VAR", "default"); got != "prod-settings" {
+        t.Errorf("got %q; want %q", got, "prod-settings")
+    }
+
+    // A dangling pointer falls back to the default
+    os.Setenv("TEST_ACTIVE_PROFILE_VAR", "TEST_PROFILE_MISSING")
+    if got := GetEnvIndirect("TEST_ACTIVE_PROFILE_VAR", "default"); got != "default" {
+        t.Errorf("got %q; want %q", got, "default")
+    }
+}
+
+// Test for falling back to another variable before the literal default
+func TestGetEnvStringOrKey(t *testing.T) {
+    // Neither key is set, so the literal default is used
+    if got := GetEnvStringOrKey("TEST_SECONDARY_URL", "TEST_PRIMARY_URL", "default"); got != "default" {
+        t.Errorf("got %q; want %q", got, "default")
+    }
+
+    os.Setenv("TEST_PRIMARY_URL", "primary")
+    defer os.Unsetenv("TEST_PRIMARY_URL")
+    if got := GetEnvStringOrKey("TEST_SECONDARY_URL", "TEST_PRIMARY_URL", "default"); got != "primary" {
+        t.Errorf("got %q; want %q", got, "primary")
+    }
+
+    os.Setenv("TEST_SECONDARY_URL", "secondary")
+    defer os.Unsetenv("TEST_SECONDARY_URL")
+    if got := GetEnvStringOrKey("TEST_SECONDARY_URL", "TEST_PRIMARY_URL", "default"); got != "secondary" {
+        t.Errorf("got %q; want %q", got, "secondary")
+    }
+}
+
+// Test for decoding percent-encoded values
+func TestGetEnvStringURLDecoded(t *testing.T) {
+    os.Setenv("TEST_URL_DECODED", "John%20Doe")
+    defer os.Unsetenv("TEST_URL_DECODED")
+    if got := GetEnvStringURLDecoded("TEST_URL_DECODED", ""); got != "John Doe" {
+        t.Errorf("got %q; want %q", got, "John Doe")
+    }
+
+    // A plus sign decodes to a space, an encoded one to a literal plus
+    os.Setenv("TEST_URL_DECODED", "a+b%2Bc")
+    if got := GetEnvStringURLDecoded("TEST_URL_DECODED", ""); got != "a b+c" {
+        t.Errorf("got %q; want %q", got, "a b+c")
+    }
+
+    if got := GetEnvStringURLDecoded("TEST_URL_DECODED_MISSING", "default"); got != "default" {
+        t.Errorf("got %q; want %q", got, "default")
+    }
+
+    // Malformed encoding panics
+    os.Setenv("TEST_URL_DECODED", "100%")
+    defer func() {
+        if recover() == nil {
+            t.Errorf("expected panic on malformed encoding")
+        }
+    }()
+    GetEnvStringURLDecoded("TEST_URL_DECODED", "")
+}
+
+// Test for resolving relative paths against a base directory
+func TestGetEnvPathAbs(t *testing.T) {
+    defer Reset()
+    base := filepath.Join(string(filepath.Separator), "etc", "app")
+    SetBaseDir(base)
+
+    os.Setenv("TEST_PATH_ABS", "certs/../certs/server.pem")
+    defer os.Unsetenv("TEST_PATH_ABS")
+    if got, want := GetEnvPathAbs("TEST_PATH_ABS", ""), filepath.Join(base, "certs", "server.pem"); got != want {
+        t.Errorf("got %q; want %q", got, want)
+    }
+
+    // Absolute paths are only cleaned
+    abs := filepath.Join(string(filepath.Separator), "var", "lib", "app")
+    os.Setenv("TEST_PATH_ABS", abs+string(filepath.Separator)+"."+string(filepath.Separator))
+    if got := GetEnvPathAbs("TEST_PATH_ABS", ""); got != abs {
+        t.Errorf("got %q; want %q", got, abs)
+    }
+
+    if got := GetEnvPathAbs("TEST_PATH_ABS_MISSING", "data"); got != "data" {
+        t.Errorf("got %q; want %q", got, "data")
+    }
+
+    // Without a base directory, the load directory is used
+    Reset()
+    os.Setenv("TEST_PATH_ABS", "data")
+    if got, want := GetEnvPathAbs("TEST_PATH_ABS", ""), filepath.Join(envDir, "data"); got != want {
+        t.Errorf("got %q; want %q", got, want)
+    }
+}
+
+// Test that numerically prefixed files are applied in numeric order
+func TestLoadNumericFileOrder(t *testing.T) {
+    dir := t.TempDir()
+    defer Load(envDir)
+
+    os.WriteFile(filepath.Join(dir, "9-base.env"), []byte("TEST_ORDER_A=base\nTEST_ORDER_B=base\n"), 0o644)
+    os.WriteFile(filepath.Join(dir, "10-override.env"), []byte("TEST_ORDER_A=override\n"), 0o644)
+    os.WriteFile(filepath.Join(dir, "20-final.env"), []byte("TEST_ORDER_A=final\nTEST_ORDER_B=final\n"), 0o644)
+    if _, err := Load(dir); err != nil {
+        t.Fatalf("Load failed: %v", err)
+    }
+
+    // 9 < 10 < 20, so 20-final.env wins for both keys
+    if got := GetEnvString("TEST_ORDER_A", ""); got != "final" {
+        t.Errorf("got %q; want %q", got, "final")
+    }
+    if got := GetEnvString("TEST_ORDER_B", ""); got != "final" {
+        t.Errorf("got %q; want %q", got, "final")
+    }
+
+    // Without the last file, 10-override.env beats 9-base.env
+    os.Remove(filepath.Join(dir, "20-final.env"))
+    Reload()
+    if got := GetEnvString("TEST_ORDER_A", ""); got != "override" {
+        t.Errorf("got %q; want %q", got, "override")
+    }
+    if got := GetEnvString("TEST_ORDER_B", ""); got != "base" {
+        t.Errorf("got %q; want %q", got, "base")
+    }
+}
+
+// Test that APP_ENV selects profile-specific files layered over base files
+func TestLoadProfileFiles(t *testing.T) {
+    dir := t.TempDir()
+    defer Load(envDir)
+
+    os.WriteFile(filepath.Join(dir, "app.env"), []byte("TEST_PROFILE_DB=base\nTEST_PROFILE_NAME=app\n"), 0o644)
+    os.WriteFile(filepath.Join(dir, "app.staging.env"), []byte("TEST_PROFILE_DB=staging\n"), 0o644)
+    os.WriteFile(filepath.Join(dir, "app.prod.env"), []byte("TEST_PROFILE_DB=prod\n"), 0o644)
+
+    // Without APP_ENV only the base file is loaded
+    Load(dir)
+    if got := GetEnvString("TEST_PROFILE_DB", ""); got != "base" {
+        t.Errorf("got %q; want %q", got, "base")
+    }
+
+    // The staging file overrides the base file
+    os.Setenv("APP_ENV", "staging")
+    defer os.Unsetenv("APP_ENV")
+    Load(dir)
+    if got := GetEnvString("TEST_PROFILE_DB", ""); got != "staging" {
+        t.Errorf("got %q; want %q", got, "staging")
+    }
+    if got := GetEnvString("TEST_PROFILE_NAME", ""); got != "app" {
+        t.Errorf("got %q; want base value %q", got, "app")
+    }
+}
+
+// Test that files of other profiles never leak into the active one
+func TestLoadProfileFilesIsolated(t *testing.T) {
+    dir := t.TempDir()
+    defer Load(envDir)
+    defer Reset()
+
+    os.WriteFile(filepath.Join(dir, "app.env"), []byte("TEST_ISOLATED_DB=base\n"), 0o644)
+    os.WriteFile(filepath.Join(dir, "app.staging.env"), []byte("TEST_ISOLATED_DB=staging\n"), 0o644)
+    os.WriteFile(filepath.Join(dir, "app.test.env"), []byte("TEST_ISOLATED_DB=test\nTEST_ISOLATED_FAKE_PAYMENTS=true\n"), 0o644)
+    defer os.Unsetenv("APP_ENV")
+
+    for _, active := range []string{"", "prod"} {
+        os.Setenv("APP_ENV", active)
+        Load(dir)
+        if got := GetEnvString("TEST_ISOLATED_DB", ""); got != "base" {
+            t.Errorf("APP_ENV=%q: got %q; want %q", active, got, "base")
+        }
+        if got := GetEnvString("TEST_ISOLATED_FAKE_PAYMENTS", ""); got != "" {
+            t.Errorf("APP_ENV=%q: test settings leaked: %q", active, got)
+        }
+    }
+
+    // Custom profile names are recognized once registered
+    os.WriteFile(filepath.Join(dir, "app.canary.env"), []byte("TEST_ISOLATED_DB=canary\n"), 0o644)
+    SetKnownProfiles("canary", "test")
+    os.Setenv("APP_ENV", "staging")
+    Load(dir)
+    if got := GetEnvString("TEST_ISOLATED_DB", ""); got != "staging" {
+        t.Errorf("got %q; want %q", got, "staging")
+    }
+}
+
+// Test that dotted file names that are not known profiles load as base files
+func TestLoadDottedBaseFiles(t *testing.T) {
+    dir := t.TempDir()
+    defer Load(envDir)
+
+    os.WriteFile(filepath.Join(dir, "my.app.env"), []byte("TEST_DOTTED_APP=my\n"), 0o644)
+    os.WriteFile(filepath.Join(dir, "10-base.local.env"), []byte("TEST_DOTTED_LOCAL=local\n"), 0o644)
+
+    for _, profile := range []string{"", "staging"} {
+        if profile != "" {
+            os.Setenv("APP_ENV", profile)
+            defer os.Unsetenv("APP_ENV")
+        }
+        Load(dir)
+        if got := GetEnvString("TEST_DOTTED_APP", ""); got != "my" {
+            t.Errorf("APP_ENV=%q: got %q; want %q", profile, got, "my")
+        }
+        if got := GetEnvString("TEST_DOTTED_LOCAL", ""); got != "local" {
+            t.Errorf("APP_ENV=%q: got %q; want %q", profile, got, "local")
+        }
+    }
+}
+
+// Test that the file named by DEFAULTS_FILE fills gaps at lowest precedence
+func TestLoadDefaultsFile(t *testing.T) {
+    dir := t.TempDir()
+    defer Load(envDir)
+
+    defaults := filepath.Join(t.TempDir(), "defaults.env")
+    os.WriteFile(defaults, []byte("TEST_DEFAULTS_HOST=default-host\nTEST_DEFAULTS_PORT=80\n"), 0o644)
+    os.WriteFile(filepath.Join(dir, "app.env"), []byte("DEFAULTS_FILE="+defaults+"\nTEST_DEFAULTS_HOST=app-host\n"), 0o644)
+    if _, err := Load(dir); err != nil {
+        t.Fatalf("Load failed: %v", err)
+    }
+
+    // Values from *.env files win, the defaults file fills the gaps
+    if got := GetEnvString("TEST_DEFAULTS_HOST", ""); got != "app-host" {
+        t.Errorf("got %q; want %q", got, "app-host")
+    }
+    if got := GetEnvString("TEST_DEFAULTS_PORT", ""); got != "80" {
+        t.Errorf("got %q; want %q", got, "80")
+    }
+
+    // The OS environment can point at the file too, relative to dir
+    os.WriteFile(filepath.Join(dir, "app.env"), []byte("TEST_DEFAULTS_HOST=app-host\n"), 0o644)
+    os.WriteFile(filepath.Join(dir, "defaults.conf"), []byte("TEST_DEFAULTS_PORT=8080\n"), 0o644)
+    os.Setenv("DEFAULTS_FILE", "defaults.conf")
+    defer os.Unsetenv("DEFAULTS_FILE")
+    if _, err := Load(dir); err != nil {
+        t.Fatalf("Load failed: %v", err)
+    }
+    if got := GetEnvString("TEST_DEFAULTS_PORT", ""); got != "8080" {
+        t.Errorf("got %q; want %q", got, "8080")
+    }
+
+    // A missing defaults file is reported but the other files still load
+    os.Setenv("DEFAULTS_FILE", "missing.env")
+    if _, err := Load(dir); err == nil {
+        t.Errorf("expected an error for a missing defaults file")
+    }
+    if got := GetEnvString("TEST_DEFAULTS_HOST", ""); got != "app-host" {
+        t.Errorf("got %q; want %q", got, "app-host")
+    }
+}
+
+// Test that a defaults file among the *.env files is only loaded as defaults
+func TestLoadDefaultsFileInDir(t *testing.T) {
+    dir := t.TempDir()
+    defer Load(envDir)
+
+    app := filepath.Join(dir, "app.env")
+    defaults := filepath.Join(dir, "defaults.env")
+    os.WriteFile(app, []byte("DEFAULTS_FILE=defaults.env\nTEST_DEFAULTS_DB=app\n"), 0o644)
+    os.WriteFile(defaults, []byte("TEST_DEFAULTS_DB=default\nTEST_DEFAULTS_CACHE=redis\n"), 0o644)
+    result, err := Load(dir)
+    if err != nil {
+        t.Fatalf("Load failed: %v", err)
+    }
+
+    // defaults.env sorts after app.env but must stay below it
+    if got := GetEnvString("TEST_DEFAULTS_DB", ""); got != "app" {
+        t.Errorf("got %q; want %q", got, "app")
+    }
+    if got := GetEnvString("TEST_DEFAULTS_CACHE", ""); got != "redis" {
+        t.Errorf("got %q; want %q", got, "redis")
+    }
+    if !reflect.DeepEqual(result.Files, []string{defaults, app}) {
+        t.Errorf("got Files %v; want %v", result.Files, []string{defaults, app})
+    }
+}
+
+// Test that Load reports loaded values, skipped lines and files read
+func TestLoadResult(t *testing.T) {
+    dir := t.TempDir()
+    defer Load(envDir)
+
+    base := filepath.Join(dir, "a.env")
+    mixed := filepath.Join(dir, "b.env")
+    os.WriteFile(base, []byte("TEST_RESULT_A=1\n"), 0o644)
+    os.WriteFile(mixed, []byte("# comment\nTEST_RESULT_B=2\nnot a pair\n\n=no-key\nTEST_RESULT_C=3\n"), 0o644)
+
+    result, err := Load(dir)
+    if err != nil {
+        t.Fatalf("Load failed: %v", err)
+    }
+    if result.Loaded != 3 {
+        t.Errorf("got Loaded %d; want 3", result.Loaded)
+    }
+    if !reflect.DeepEqual(result.Files, []string{base, mixed}) {
+        t.Errorf("got Files %v; want %v", result.Files, []string{base, mixed})
+    }
+    want := []ParseError{
+        {File: mixed, Line: 3, Text: "not a pair", Reason: "missing '='"},
+        {File: mixed, Line: 5, Text: "=no-key", Reason: "missing key"},
+    }
+    if !reflect.DeepEqual(result.Skipped, want) {
+        t.Errorf("got Skipped %+v; want %+v", result.Skipped, want)
+    }
+    if got := result.Skipped[0].Error(); got != mixed+":3: missing '='" {
+        t.Errorf("got %q; want %q", got, mixed+":3: missing '='")
+    }
+}
+
+// Test for keys repeated within a single file in lenient and strict mode
+func TestSetStrictDuplicates(t *testing.T) {
+    dir := t.TempDir()
+    defer Load(envDir)
+    defer Reset()
+
+    file := filepath.Join(dir, "app.env")
+    os.WriteFile(file, []byte("TEST_DUP=first\nTEST_DUP_OTHER=x\nTEST_DUP=second\n"), 0o644)
+    os.WriteFile(filepath.Join(dir, "b.env"), []byte("TEST_DUP_OTHER=y\n"), 0o644)
+
+    // Lenient by default: the last definition wins
+    result, err := Load(dir)
+    if err != nil {
+        t.Fatalf("Load failed: %v", err)
+    }
+    if len(result.Skipped) != 0 {
+        t.Errorf("got Skipped %+v; want none", result.Skipped)
+    }
+    if got := GetEnvString("TEST_DUP", ""); got != "second" {
+        t.Errorf("got %q; want %q", got, "second")
+    }
+
+    // Strict: the first definition is kept and the repeat is an error
+    SetStrictDuplicates(true)
+    result, err = Load(dir)
+    want := ParseError{File: file, Line: 3, Text: "TEST_DUP=second", Reason: "duplicate key"}
+    if err == nil || err.Error() != want.Error() {
+        t.Errorf("got error %v; want %v", err, want)
+    }
+    if !reflect.DeepEqual(result.Skipped, []ParseError{want}) {
+        t.Errorf("got Skipped %+v; want %+v", result.Skipped, []ParseError{want})
+    }
+    if got := GetEnvString("TEST_DUP", ""); got != "first" {
+        t.Errorf("got %q; want %q", got, "first")
+    }
+
+    // Keys repeated across files are not duplicates
+    if got := GetEnvString("TEST_DUP_OTHER", ""); got != "y" {
+        t.Errorf("got %q; want %q", got, "y")
+    }
+}
+
+// Test for keeping significant whitespace in values
+func TestSetTrimValues(t *testing.T) {
+    dir := t.TempDir()
+    defer Load(envDir)
+    defer Reset()
+
+    os.WriteFile(filepath.Join(dir, "app.env"), []byte("TEST_TRIM_SEP= \nTEST_TRIM_PAD =  padded  \n"), 0o644)
+
+    // Values are trimmed by default
+    Load(dir)
+    if got := GetEnvString("TEST_TRIM_PAD", ""); got != "padded" {
+        t.Errorf("got %q; want %q", got, "padded")
+    }
+    if got, ok := lookup("TEST_TRIM_SEP"); !ok || got != "" {
+        t.Errorf("got %q, %v; want %q, true", got, ok, "")
+    }
+
+    // Without trimming, values keep their exact whitespace
+    SetTrimValues(false)
+    Load(dir)
+    if got := GetEnvString("TEST_TRIM_PAD", ""); got != "  padded  " {
+        t.Errorf("got %q; want %q", got, "  padded  ")
+    }
+    if got := GetEnvString("TEST_TRIM_SEP", ""); got != " " {
+        t.Errorf("got %q; want %q", got, " ")
+    }
+}
+
+// Test for collapsing bracketed indices into a delimited array
+func TestLoadIndexedKeys(t *testing.T) {
+    dir := t.TempDir()
+    defer Load(envDir)
+
+    os.WriteFile(filepath.Join(dir, "app.env"), []byte(
+        "TEST_IDX[1]=b\nTEST_IDX[0]=a\nTEST_IDX[2]=c\n"+
+            "TEST_GAP[0]=x\nTEST_GAP[3]=y\n"+
+            "TEST_COMMA[0]=1,2\nTEST_COMMA[1]=3\n"+
+            "TEST_NAMES[0]=O'Brien\nTEST_NAMES[1]=Smith\n"+
+            "TEST_MIXED[0]=D'Arcy, \"Jr.\"\nTEST_MIXED[1]=Smith\n"), 0o644)
+    Load(dir)
+
+    // Indices are ordered regardless of their order in the file
+    if got := GetEnvArrayString("TEST_IDX", ",", nil); !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+        t.Errorf("got %v; want %v", got, []string{"a", "b", "c"})
+    }
+
+    // Gaps are skipped
+    if got := GetEnvArrayString("TEST_GAP", ",", nil); !reflect.DeepEqual(got, []string{"x", "y"}) {
+        t.Errorf("got %v; want %v", got, []string{"x", "y"})
+    }
+    if _, ok := lookup("TEST_GAP[3]"); ok {
+        t.Errorf("indexed key should not remain after collapsing")
+    }
+
+    // Elements containing the delimiter are quoted
+    if got := GetEnvArrayStringQuoted("TEST_COMMA", ",", nil); !reflect.DeepEqual(got, []string{"1,2", "3"}) {
+        t.Errorf("got %v; want %v", got, []string{"1,2", "3"})
+    }
+
+    // Other elements, apostrophes included, are joined as is
+    if got := GetEnvString("TEST_NAMES", ""); got != "O'Brien,Smith" {
+        t.Errorf("got %q; want %q", got, "O'Brien,Smith")
+    }
+    if got := GetEnvArrayString("TEST_NAMES", ",", nil); !reflect.DeepEqual(got, []string{"O'Brien", "Smith"}) {
+        t.Errorf("got %v; want %v", got, []string{"O'Brien", "Smith"})
+    }
+    want := []string{`D'Arcy, "Jr."`, "Smith"}
+    if got := GetEnvArrayStringQuoted("TEST_MIXED", ",", nil); !reflect.DeepEqual(got, want) {
+        t.Errorf("got %v; want %v", got, want)
+    }
+}
+
+// Test for reading a value from stdin when it is set to "-"
+func TestGetEnvStringOrStdin(t *testing.T) {
+    oldStdin, oldOutput := stdin, promptOutput
+    defer func() { stdin, promptOutput = oldStdin, oldOutput }()
+    var prompts strings.Builder
+    stdin = strings.NewReader("s3cret\r\nsecond\n")
+    promptOutput = &prompts
+    defer os.Unsetenv("TEST_STDIN_SECRET")
+    defer os.Unsetenv("TEST_STDIN_PLAIN")
+
+    // Ordinary values are returned as is, without prompting
+    os.Setenv("TEST_STDIN_PLAIN", "value")
+    if got := GetEnvStringOrStdin("TEST_STDIN_PLAIN", "Plain: ", "default"); got != "value" {
+        t.Errorf("got %q; want %q", got, "value")
+    }
+
+    // The sentinel prompts once and reads a single line
+    os.Setenv("TEST_STDIN_SECRET", "-")
+    for i := 0; i < 2; i++ {
+        if got := GetEnvStringOrStdin("TEST_STDIN_SECRET", "Secret: ", "default"); got != "s3cret" {
+            t.Errorf("got %q; want %q", got, "s3cret")
+        }
+    }
+    if prompts.String() != "Secret: " {
+        t.Errorf("got prompts %q; want %q", prompts.String(), "Secret: ")
+    }
+
+    // Reset forgets the value, so the next call prompts again
+    Reset()
+    if got := GetEnvStringOrStdin("TEST_STDIN_SECRET", "Secret: ", "default"); got != "second" {
+        t.Errorf("got %q after Reset; want %q", got, "second")
+    }
+
+    // Read failures go through the error handler
+    defer Reset()
+    var failed error
+    SetErrorHandler(func(key string, err error) { failed = err })
+    stdin = strings.NewReader("")
+    os.Setenv("TEST_STDIN_EMPTY", "-")
+    defer os.Unsetenv("TEST_STDIN_EMPTY")
+    if got := GetEnvStringOrStdin("TEST_STDIN_EMPTY", "Empty: ", "default"); got != "default" || failed == nil {
+        t.Errorf("got (%q, %v); want default and an error", got, failed)
+    }
+}
+
+// Test for splitting PATH-like variables on the OS separator
+func TestGetEnvPathList(t *testing.T) {
+    defer func(sep string) { pathListSeparator = sep }(pathListSeparator)
+    defer os.Unsetenv("TEST_PATH_LIST")
+
+    // Unix-style separator
+    pathListSeparator = ":"
+    os.Setenv("TEST_PATH_LIST", "/usr/bin::/bin")
+    if got := GetEnvPathList("TEST_PATH_LIST", nil); !reflect.DeepEqual(got, []string{"/usr/bin", "/bin"}) {
+        t.Errorf("got %v; want %v", got, []string{"/usr/bin", "/bin"})
+    }
+
+    // Windows-style separator keeps drive letters intact
+    pathListSeparator = ";"
+    os.Setenv("TEST_PATH_LIST", `C:\Windows;C:\Tools`)
+    if got := GetEnvPathList("TEST_PATH_LIST", nil); !reflect.DeepEqual(got, []string{`C:\Windows`, `C:\Tools`}) {
+        t.Errorf("got %v; want %v", got, []string{`C:\Windows`, `C:\Tools`})
+    }
+
+    // No entries falls back to the default
+    os.Setenv("TEST_PATH_LIST", ";;")
+    if got := GetEnvPathList("TEST_PATH_LIST", []string{"/default"}); !reflect.DeepEqual(got, []string{"/default"}) {
+        t.Errorf("got %v; want %v", got, []string{"/default"})
+    }
+}
+
+// Test for serializing a slice back into delimited form
+func TestJoinArray(t *testing.T) {
+    if got := JoinArray([]string{"a", "b", "c"}, ","); got != "a,b,c" {
+        t.Errorf("got %q; want %q", got, "a,b,c")
+    }
+    if got := JoinArray([]string{"a,b", "c"}, ","); got != `"a,b",c` {
+        t.Errorf("got %q; want %q", got, `"a,b",c`)
+    }
+
+    // Joined values round-trip through the quote-aware getter
+    values := []string{"plain", "with,comma", `say "hi"`, "it's", ""}
+    os.Setenv("TEST_JOIN_ARRAY", JoinArray(values, ","))
+    defer os.Unsetenv("TEST_JOIN_ARRAY")
+    if got := GetEnvArrayStringQuoted("TEST_JOIN_ARRAY", ",", nil); !reflect.DeepEqual(got, values) {
+        t.Errorf("got %q; want %q", got, values)
+    }
+}
+
+// Test for parsing measurements with SI prefixes
+func TestGetEnvSIValue(t *testing.T) {
+    defer os.Unsetenv("TEST_SI")
+
+    cases := []struct {
+        val  string
+        want float64
+        unit string
+    }{
+        {"2.4GHz", 2.4e9, "Hz"},
+        {"3.3mV", 3.3e-3, "V"},
+        {"10kW", 1e4, "W"},
+        {"47µF", 47e-6, "F"},
+        {"5m", 5, "m"},
+        {"1.5e3 Hz", 1.5e3, "Hz"},
+        {"101325Pa", 101325, "Pa"},
+        {"1mol", 1, "mol"},
+        {"2cd", 2, "cd"},
+        {"5min", 5, "min"},
+        {"3kPa", 3e3, "Pa"},
+        {"2mmol", 2e-3, "mol"},
+    }
+    for _, c := range cases {
+        os.Setenv("TEST_SI", c.val)
+        got, unit := GetEnvSIValue("TEST_SI", 0)
+        if math.Abs(got-c.want) > math.Abs(c.want)*1e-12 || unit != c.unit {
+            t.Errorf("for %q got (%g, %q); want (%g, %q)", c.val, got, unit, c.want, c.unit)
+        }
+    }
+
+    // A value without a number panics
+    os.Setenv("TEST_SI", "GHz")
+    defer func() {
+        if recover() == nil {
+            t.Errorf("expected panic for malformed measurement")
+        }
+    }()
+    GetEnvSIValue("TEST_SI", 0)
+}
+
+// Test for expanding references with a caller-supplied mapping
+func TestGetEnvStringExpandFunc(t *testing.T) {
+    os.Setenv("TEST_EXPAND_FUNC", "postgres://${USER}@${HOST}/app")
+    defer os.Unsetenv("TEST_EXPAND_FUNC")
+
+    vars := map[string]string{"USER": "admin", "HOST": "db.local"}
+    mapping := func(name string) string { return vars[name] }
+
+    got := GetEnvStringExpandFunc("TEST_EXPAND_FUNC", "", mapping)
+    if want := "postgres://admin@db.local/app"; got != want {
+        t.Errorf("got %q; want %q", got, want)
+    }
+
+    // Unresolved references expand to what the mapping returns
+    delete(vars, "HOST")
+    got = GetEnvStringExpandFunc("TEST_EXPAND_FUNC", "", mapping)
+    if want := "postgres://admin@/app"; got != want {
+        t.Errorf("got %q; want %q", got, want)
+    }
+}
+
+// Test for retrieving an array of network ports
+func TestGetEnvArrayPort(t *testing.T) {
+    os.Setenv("TEST_PORTS", "8080,8081,0,65535")
+    defer os.Unsetenv("TEST_PORTS")
+
+    got := GetEnvArrayPort("TEST_PORTS", ",", nil)
+    if want := []int{8080, 8081, 0, 65535}; !reflect.DeepEqual(got, want) {
+        t.Errorf("got %v; want %v", got, want)
+    }
+
+    // An out-of-range element panics
+    os.Setenv("TEST_PORTS", "8080,70000")
+    defer func() {
+        if recover() == nil {
+            t.Errorf("expected panic for out-of-range port")
+        }
+    }()
+    GetEnvArrayPort("TEST_PORTS", ",", nil)
+}
+
+// Test for retrieving a list of validated email addresses
+func TestGetEnvArrayEmail(t *testing.T) {
+    os.Setenv("TEST_ADMINS", "a@x.com, b@y.com ,Jane Doe <jane@z.com>")
+    defer os.Unsetenv("TEST_ADMINS")
+
+    got := GetEnvArrayEmail("TEST_ADMINS", ",", nil)
+    if want := []string{"a@x.com", "b@y.com", "Jane Doe <jane@z.com>"}; !reflect.DeepEqual(got, want) {
+        t.Errorf("got %v; want %v", got, want)
+    }
+
+    if got := GetEnvArrayEmail("TEST_ADMINS_MISSING", ",", []string{"root@localhost"}); !reflect.DeepEqual(got, []string{"root@localhost"}) {
+        t.Errorf("got %v; want default", got)
+    }
+
+    // An invalid address panics
+    os.Setenv("TEST_ADMINS", "a@x.com,not-an-email")
+    defer func() {
+        if r := recover(); r == nil || !strings.Contains(r.(string), "not-an-email") {
+            t.Errorf("got panic %v; want one naming not-an-email", r)
+        }
+    }()
+    GetEnvArrayEmail("TEST_ADMINS", ",", nil)
+}
+
+// Test for serving OS lookups from a snapshot refreshed on reload
+func TestSnapshotOSEnv(t *testing.T) {
+    defer Load(envDir)
+    defer Reset()
+
+    os.Setenv("TEST_SNAPSHOT", "before")
+    defer os.Unsetenv("TEST_SNAPSHOT")
+    SnapshotOSEnv()
+
+    if got := GetEnvString("TEST_SNAPSHOT", ""); got != "before" {
+        t.Errorf("got %q; want %q", got, "before")
+    }
+
+    // Live changes are not visible until the snapshot is refreshed
+    os.Setenv("TEST_SNAPSHOT", "after")
+    if got := GetEnvString("TEST_SNAPSHOT", ""); got != "before" {
+        t.Errorf("got %q; want snapshot value %q", got, "before")
+    }
+    Reload()
+    if got := GetEnvString("TEST_SNAPSHOT", ""); got != "after" {
+        t.Errorf("got %q after reload; want %q", got, "after")
+    }
+
+    // Reset returns to live lookups
+    Reset()
+    os.Setenv("TEST_SNAPSHOT", "live")
+    if got := GetEnvString("TEST_SNAPSHOT", ""); got != "live" {
+        t.Errorf("got %q after Reset; want %q", got, "live")
+    }
+}
+
+// Test that Reload can refresh the snapshot while getters read, e.g. under -race
+func TestSnapshotOSEnvConcurrentReload(t *testing.T) {
+    defer Load(envDir)
+    defer Reset()
+    os.Setenv("TEST_SNAPSHOT_RACE", "value")
+    defer os.Unsetenv("TEST_SNAPSHOT_RACE")
+    SnapshotOSEnv()
+
+    done := make(chan struct{})
+    go func() {
+        defer close(done)
+        for i := 0; i < 100; i++ {
+            Reload()
+        }
+    }()
+    for i := 0; i < 1000; i++ {
+        if got := GetEnvString("TEST_SNAPSHOT_RACE", ""); got != "value" {
+            t.Fatalf("got %q; want %q", got, "value")
+        }
+    }
+    <-done
+}
+
+// Benchmark for resolving an OS variable with live lookups
+func BenchmarkLookupLive(b *testing.B) {
+    os.Setenv("BENCH_SNAPSHOT", "value")
+    defer os.Unsetenv("BENCH_SNAPSHOT")
+    for i := 0; i < b.N; i++ {
+        GetEnvString("BENCH_SNAPSHOT", "")
+    }
+}
+
+// Benchmark for resolving an OS variable from a snapshot
+func BenchmarkLookupSnapshot(b *testing.B) {
+    os.Setenv("BENCH_SNAPSHOT", "value")
+    defer os.Unsetenv("BENCH_SNAPSHOT")
+    SnapshotOSEnv()
+    defer Reset()
+    for i := 0; i < b.N; i++ {
+        GetEnvString("BENCH_SNAPSHOT", "")
+    }
+}
@@ -0,0 +1,76 @@
+package env
+
+// Candidate is one source's value for a key, as reported by Explain. Key is
+// the name the value was found under, which differs from the key asked for
+// when a prefix set via SetPrefix, a key normalizer or an alias applies.
+type Candidate struct {
+	Key    string
+	Source string
+	Value  string
+	Winner bool
+}
+
+// Explain lists every candidate value for key in the order getters try them.
+// Each name getters look up, the prefixed one set via SetPrefix first and
+// then key itself, both normalized, followed by the same for each alias
+// registered via AddAlias, is searched in values applied via
+// ApplyOverrides ("override"), the OS environment ("os"), values set in
+// memory ("memory") and each loaded file from last to first (by path). The
+// default ("default") comes last. The candidate that getters resolve is
+// marked as the winner; the default entry wins only when no other source
+// defines key. Values are shown as stored, before any decryption. It is safe
+// to call while other goroutines set values or reload.
+func Explain(key string) []Candidate {
+	// The in-memory stores and sources are written under envMu
+	envMu.Lock()
+	defer envMu.Unlock()
+
+	// Names are listed in the order resolve tries them: key, then its aliases
+	var candidates []Candidate
+	for _, name := range lookupNames(key) {
+		candidates = append(candidates, explainName(name)...)
+	}
+	for _, alias := range aliases[key] {
+		for _, name := range lookupNames(alias) {
+			candidates = append(candidates, explainName(name)...)
+		}
+	}
+	candidates = append(candidates, Candidate{Key: key, Source: "default"})
+	candidates[0].Winner = true
+	return candidates
+}
+
+// lookupNames returns the normalized names findPrefixed tries for key, in
+// order and without repeats.
+func lookupNames(key string) []string {
+	names := []string{normalized(keyPrefix + key)}
+	if keyPrefix != "" {
+		if name := normalized(key); name != names[0] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// explainName lists the candidates for a single normalized name in the order
+// find consults its sources. envMu must be held.
+func explainName(name string) []Candidate {
+	var candidates []Candidate
+	if val, ok := overrides()[name]; ok {
+		candidates = append(candidates, Candidate{Key: name, Source: "override", Value: val})
+	}
+	if val, ok := lookupOS(name); ok {
+		candidates = append(candidates, Candidate{Key: name, Source: "os", Value: val})
+	}
+	if val, ok := transientEnv[name]; ok {
+		candidates = append(candidates, Candidate{Key: name, Source: "memory", Value: val})
+	} else if val, ok := persistentEnv[name]; ok {
+		candidates = append(candidates, Candidate{Key: name, Source: "memory", Value: val})
+	}
+	for i := len(loadedSources) - 1; i >= 0; i-- {
+		if val, ok := loadedSources[i].values[name]; ok {
+			candidates = append(candidates, Candidate{Key: name, Source: loadedSources[i].name, Value: val})
+		}
+	}
+	return candidates
+}
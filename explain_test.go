@@ -0,0 +1,138 @@
+package env
+
+import (
+    "os"
+    "path/filepath"
+    "reflect"
+    "strconv"
+    "strings"
+    "sync"
+    "testing"
+)
+
+// Test for listing all candidate values of a key and the winning source
+func TestExplain(t *testing.T) {
+    dir := t.TempDir()
+    defer Load(envDir)
+
+    base := filepath.Join(dir, "a.env")
+    override := filepath.Join(dir, "b.env")
+    os.WriteFile(base, []byte("TEST_EXPLAIN=from-a\n"), 0o644)
+    os.WriteFile(override, []byte("TEST_EXPLAIN=from-b\n"), 0o644)
+    if _, err := Load(dir); err != nil {
+        t.Fatalf("Load failed: %v", err)
+    }
+
+    // Without an OS value the last file wins
+    got := Explain("TEST_EXPLAIN")
+    want := []Candidate{
+        {Key: "TEST_EXPLAIN", Source: override, Value: "from-b", Winner: true},
+        {Key: "TEST_EXPLAIN", Source: base, Value: "from-a"},
+        {Key: "TEST_EXPLAIN", Source: "default"},
+    }
+    if len(got) != len(want) {
+        t.Fatalf("got %v; want %v", got, want)
+    }
+    for i := range want {
+        if got[i] != want[i] {
+            t.Errorf("candidate %d: got %+v; want %+v", i, got[i], want[i])
+        }
+    }
+
+    // An OS value takes precedence over both files
+    os.Setenv("TEST_EXPLAIN", "from-os")
+    defer os.Unsetenv("TEST_EXPLAIN")
+    got = Explain("TEST_EXPLAIN")
+    if len(got) != 4 || got[0].Source != "os" || !got[0].Winner || got[1].Winner {
+        t.Errorf("got %+v; want os as the winning first candidate", got)
+    }
+    if GetEnvString("TEST_EXPLAIN", "") != got[0].Value {
+        t.Errorf("winner %q does not match the resolved value", got[0].Value)
+    }
+
+    // Only the default is listed for unknown keys
+    got = Explain("TEST_EXPLAIN_MISSING")
+    if len(got) != 1 || got[0].Source != "default" || !got[0].Winner {
+        t.Errorf("got %+v; want a single winning default", got)
+    }
+}
+
+// Test that Explain follows the prefix and normalizer getters use
+func TestExplainPrefix(t *testing.T) {
+    defer Reset()
+    SetPrefix("MYAPP_")
+    os.Setenv("MYAPP_TEST_EXPLAIN_PORT", "1")
+    os.Setenv("TEST_EXPLAIN_PORT", "2")
+    defer os.Unsetenv("MYAPP_TEST_EXPLAIN_PORT")
+    defer os.Unsetenv("TEST_EXPLAIN_PORT")
+
+    got := Explain("TEST_EXPLAIN_PORT")
+    want := []Candidate{
+        {Key: "MYAPP_TEST_EXPLAIN_PORT", Source: "os", Value: "1", Winner: true},
+        {Key: "TEST_EXPLAIN_PORT", Source: "os", Value: "2"},
+        {Key: "TEST_EXPLAIN_PORT", Source: "default"},
+    }
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("got %+v; want %+v", got, want)
+    }
+    if resolved := GetEnvString("TEST_EXPLAIN_PORT", ""); resolved != got[0].Value {
+        t.Errorf("winner %q does not match the resolved value %q", got[0].Value, resolved)
+    }
+
+    // Keys are normalized as in lookups
+    SetPrefix("")
+    SetNormalizeKeys(strings.ToUpper)
+    got = Explain("test_explain_port")
+    if got[0].Key != "TEST_EXPLAIN_PORT" || got[0].Value != "2" || !got[0].Winner {
+        t.Errorf("got %+v; want the normalized OS value as winner", got)
+    }
+}
+
+// Test that Explain lists alias candidates after the key's own
+func TestExplainAlias(t *testing.T) {
+    defer Reset()
+    AddAlias("TEST_EXPLAIN_NEWK", "TEST_EXPLAIN_OLDK")
+    os.Setenv("TEST_EXPLAIN_OLDK", "old")
+    defer os.Unsetenv("TEST_EXPLAIN_OLDK")
+
+    // Only the alias is set, so it wins
+    got := Explain("TEST_EXPLAIN_NEWK")
+    want := []Candidate{
+        {Key: "TEST_EXPLAIN_OLDK", Source: "os", Value: "old", Winner: true},
+        {Key: "TEST_EXPLAIN_NEWK", Source: "default"},
+    }
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("got %+v; want %+v", got, want)
+    }
+    if resolved := GetEnvString("TEST_EXPLAIN_NEWK", ""); resolved != got[0].Value {
+        t.Errorf("winner %q does not match the resolved value %q", got[0].Value, resolved)
+    }
+
+    // The key itself takes precedence over its alias
+    os.Setenv("TEST_EXPLAIN_NEWK", "new")
+    defer os.Unsetenv("TEST_EXPLAIN_NEWK")
+    got = Explain("TEST_EXPLAIN_NEWK")
+    if len(got) != 3 || got[0].Key != "TEST_EXPLAIN_NEWK" || !got[0].Winner || got[1].Key != "TEST_EXPLAIN_OLDK" || got[1].Winner {
+        t.Errorf("got %+v; want the key winning over its alias", got)
+    }
+}
+
+// Test that Explain can run while values are being set, e.g. under -race
+func TestExplainConcurrent(t *testing.T) {
+    var wg sync.WaitGroup
+    wg.Add(2)
+    go func() {
+        defer wg.Done()
+        for i := 0; i < 1000; i++ {
+            SetEnvTransient("TEST_EXPLAIN_CONCURRENT_"+strconv.Itoa(i%10), "value")
+        }
+    }()
+    go func() {
+        defer wg.Done()
+        for i := 0; i < 1000; i++ {
+            Explain("TEST_EXPLAIN_CONCURRENT_" + strconv.Itoa(i%10))
+        }
+    }()
+    wg.Wait()
+    Reload()
+}
@@ -1,3 +1,11 @@
 module github.com/elum-utils/env
 
 go 1.24.1
+
+require (
+	github.com/BurntSushi/toml v1.6.0
+	golang.org/x/term v0.34.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require golang.org/x/sys v0.35.0 // indirect
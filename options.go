@@ -0,0 +1,193 @@
+package env
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// ArrayOption customizes how array getters such as GetEnvArrayString and
+// GetEnvArrayInt split a value into elements.
+type ArrayOption func(*arrayOptions)
+
+// arrayOptions holds the settings collected from ArrayOption values.
+type arrayOptions struct {
+	trimDelimiters bool
+	maxLength      int
+	truncate       bool
+	expand         bool
+	keepEmpty      bool
+	split          string
+	steps          []func(o *arrayOptions, values []string) []string
+}
+
+// TrimDelimiters strips leading and trailing delimiters before splitting, so
+// ",a,b," yields [a b]. Empty elements between delimiters are still kept.
+func TrimDelimiters() ArrayOption {
+	return func(o *arrayOptions) {
+		o.trimDelimiters = true
+	}
+}
+
+// MaxElementLength rejects values containing an element longer than n
+// characters. The getter panics (or reports to the error handler) on violation.
+func MaxElementLength(n int) ArrayOption {
+	return func(o *arrayOptions) {
+		o.maxLength = n
+		o.truncate = false
+	}
+}
+
+// TruncateElements shortens every element to at most n characters instead of
+// rejecting longer ones.
+func TruncateElements(n int) ArrayOption {
+	return func(o *arrayOptions) {
+		o.maxLength = n
+		o.truncate = true
+	}
+}
+
+// ExpandElements runs os.Expand on every element after splitting, resolving
+// ${VAR} and $VAR references against the OS environment and loaded *.env files.
+// Unknown references expand to an empty string.
+func ExpandElements() ArrayOption {
+	return func(o *arrayOptions) {
+		o.expand = true
+	}
+}
+
+// KeepEmpty guarantees that empty elements are preserved, so "a,,b" yields
+// [a "" b]. It takes precedence over options that drop empty elements.
+func KeepEmpty() ArrayOption {
+	return func(o *arrayOptions) {
+		o.keepEmpty = true
+	}
+}
+
+// WithSplit sets the delimiter used to split the value. It overrides the split
+// argument of the array getters and DefaultDelimiter for GetArrayString.
+func WithSplit(sep string) ArrayOption {
+	return func(o *arrayOptions) {
+		o.split = sep
+	}
+}
+
+// Trim removes surrounding whitespace from every element.
+func Trim() ArrayOption {
+	return step(func(_ *arrayOptions, values []string) []string {
+		for i, v := range values {
+			values[i] = strings.TrimSpace(v)
+		}
+		return values
+	})
+}
+
+// Lower converts every element to lower case.
+func Lower() ArrayOption {
+	return step(func(_ *arrayOptions, values []string) []string {
+		for i, v := range values {
+			values[i] = strings.ToLower(v)
+		}
+		return values
+	})
+}
+
+// Dedupe removes repeated elements, keeping the first occurrence of each.
+func Dedupe() ArrayOption {
+	return step(func(_ *arrayOptions, values []string) []string {
+		seen := make(map[string]bool, len(values))
+		result := values[:0]
+		for _, v := range values {
+			if !seen[v] {
+				seen[v] = true
+				result = append(result, v)
+			}
+		}
+		return result
+	})
+}
+
+// DropEmpty removes empty elements. It has no effect when KeepEmpty is set.
+func DropEmpty() ArrayOption {
+	return step(func(o *arrayOptions, values []string) []string {
+		if o.keepEmpty {
+			return values
+		}
+		result := values[:0]
+		for _, v := range values {
+			if v != "" {
+				result = append(result, v)
+			}
+		}
+		return result
+	})
+}
+
+// step returns an ArrayOption that appends fn to the normalization pipeline.
+// Pipeline steps run after splitting, in the order the options were given.
+func step(fn func(o *arrayOptions, values []string) []string) ArrayOption {
+	return func(o *arrayOptions) {
+		o.steps = append(o.steps, fn)
+	}
+}
+
+// GetArrayString retrieves a delimited environment variable as a string slice,
+// processed by opts in the order given, e.g.
+//
+//	GetArrayString("TAGS", Trim(), Lower(), Dedupe())
+//
+// The value is split on DefaultDelimiter unless WithSplit is given. Returns nil
+// if the variable is not set.
+func GetArrayString(key string, opts ...ArrayOption) []string {
+	return GetEnvArrayString(key, DefaultDelimiter, nil, opts...)
+}
+
+// splitArray splits val on split and applies opts to the result. The returned
+// error describes which option the value of key violated.
+func splitArray(key, val, split string, opts []ArrayOption) ([]string, error) {
+	var o arrayOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.split != "" {
+		split = o.split
+	}
+
+	if o.trimDelimiters && split != "" {
+		for strings.HasPrefix(val, split) {
+			val = val[len(split):]
+		}
+		for strings.HasSuffix(val, split) {
+			val = val[:len(val)-len(split)]
+		}
+		if val == "" {
+			return []string{}, nil
+		}
+	}
+
+	values := strings.Split(val, split)
+	if o.expand {
+		for i, v := range values {
+			values[i] = os.Expand(v, func(name string) string {
+				resolved, _ := lookup(name)
+				return resolved
+			})
+		}
+	}
+	for _, fn := range o.steps {
+		values = fn(&o, values)
+	}
+	if o.maxLength > 0 {
+		for i, v := range values {
+			if utf8.RuneCountInString(v) <= o.maxLength {
+				continue
+			}
+			if !o.truncate {
+				return nil, fmt.Errorf("Environment variable %s array contains an element longer than %d characters at index %d", key, o.maxLength, i)
+			}
+			values[i] = string([]rune(v)[:o.maxLength])
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,126 @@
+package env
+
+import (
+    "os"
+    "reflect"
+    "testing"
+)
+
+// Test for trimming leading and trailing delimiters before splitting
+func TestTrimDelimiters(t *testing.T) {
+    defer os.Unsetenv("TEST_TRIM_DELIMITERS")
+
+    cases := map[string][]string{
+        ",a,b":   {"a", "b"},
+        "a,b,":   {"a", "b"},
+        ",,a,b,": {"a", "b"},
+        ",a,,b,": {"a", "", "b"},
+    }
+    for val, want := range cases {
+        os.Setenv("TEST_TRIM_DELIMITERS", val)
+        got := GetEnvArrayString("TEST_TRIM_DELIMITERS", ",", nil, TrimDelimiters())
+        if !reflect.DeepEqual(got, want) {
+            t.Errorf("for %q got %q; want %q", val, got, want)
+        }
+    }
+
+    // Without the option empty elements are preserved
+    os.Setenv("TEST_TRIM_DELIMITERS", ",a,b,")
+    got := GetEnvArrayString("TEST_TRIM_DELIMITERS", ",", nil)
+    if want := []string{"", "a", "b", ""}; !reflect.DeepEqual(got, want) {
+        t.Errorf("got %q; want %q", got, want)
+    }
+
+    // The option applies to typed array getters too
+    os.Setenv("TEST_TRIM_DELIMITERS", "1,2,3,")
+    if got := GetEnvArrayInt("TEST_TRIM_DELIMITERS", ",", nil, TrimDelimiters()); !reflect.DeepEqual(got, []int{1, 2, 3}) {
+        t.Errorf("got %v; want [1 2 3]", got)
+    }
+}
+
+// Test for capping the length of array elements
+func TestMaxElementLength(t *testing.T) {
+    os.Setenv("TEST_MAX_ELEMENT", "ab,abcd,abc")
+    defer os.Unsetenv("TEST_MAX_ELEMENT")
+
+    // Elements within the limit are returned unchanged
+    got := GetEnvArrayString("TEST_MAX_ELEMENT", ",", nil, MaxElementLength(4))
+    if want := []string{"ab", "abcd", "abc"}; !reflect.DeepEqual(got, want) {
+        t.Errorf("got %q; want %q", got, want)
+    }
+
+    // Truncation shortens over-length elements
+    got = GetEnvArrayString("TEST_MAX_ELEMENT", ",", nil, TruncateElements(3))
+    if want := []string{"ab", "abc", "abc"}; !reflect.DeepEqual(got, want) {
+        t.Errorf("got %q; want %q", got, want)
+    }
+
+    // Over-length elements panic without truncation
+    defer func() {
+        if recover() == nil {
+            t.Errorf("expected panic for over-length element")
+        }
+    }()
+    GetEnvArrayString("TEST_MAX_ELEMENT", ",", nil, MaxElementLength(3))
+}
+
+// Test for expanding variable references in array elements
+func TestExpandElements(t *testing.T) {
+    os.Setenv("TEST_EXPAND_ROOT", "/srv")
+    os.Setenv("TEST_EXPAND_PATHS", "${TEST_EXPAND_ROOT}/a,$TEST_EXPAND_ROOT/b,/literal")
+    defer os.Unsetenv("TEST_EXPAND_ROOT")
+    defer os.Unsetenv("TEST_EXPAND_PATHS")
+
+    got := GetEnvArrayString("TEST_EXPAND_PATHS", ",", nil, ExpandElements())
+    want := []string{"/srv/a", "/srv/b", "/literal"}
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("got %q; want %q", got, want)
+    }
+
+    // Without the option references are left as-is
+    got = GetEnvArrayString("TEST_EXPAND_PATHS", ",", nil)
+    if got[0] != "${TEST_EXPAND_ROOT}/a" {
+        t.Errorf("got %q; want unexpanded reference", got[0])
+    }
+}
+
+// Test for explicitly preserving empty array elements
+func TestKeepEmpty(t *testing.T) {
+    os.Setenv("TEST_KEEP_EMPTY", "a,,b")
+    defer os.Unsetenv("TEST_KEEP_EMPTY")
+
+    want := []string{"a", "", "b"}
+    for _, opts := range [][]ArrayOption{nil, {KeepEmpty()}} {
+        got := GetEnvArrayString("TEST_KEEP_EMPTY", ",", nil, opts...)
+        if !reflect.DeepEqual(got, want) {
+            t.Errorf("with %d options got %q; want %q", len(opts), got, want)
+        }
+    }
+}
+
+// Test for composing normalizers on array getters
+func TestGetArrayString(t *testing.T) {
+    os.Setenv("TEST_PIPELINE", " Go ; rust;GO ;; Zig ")
+    defer os.Unsetenv("TEST_PIPELINE")
+
+    got := GetArrayString("TEST_PIPELINE", WithSplit(";"), Trim(), Lower(), Dedupe(), DropEmpty())
+    if want := []string{"go", "rust", "zig"}; !reflect.DeepEqual(got, want) {
+        t.Errorf("got %q; want %q", got, want)
+    }
+
+    // Options run in order: deduping before lowering keeps case variants
+    got = GetArrayString("TEST_PIPELINE", WithSplit(";"), Trim(), Dedupe(), Lower())
+    if want := []string{"go", "rust", "go", "", "zig"}; !reflect.DeepEqual(got, want) {
+        t.Errorf("got %q; want %q", got, want)
+    }
+
+    // KeepEmpty takes precedence over DropEmpty
+    got = GetArrayString("TEST_PIPELINE", WithSplit(";"), Trim(), DropEmpty(), KeepEmpty())
+    if len(got) != 5 {
+        t.Errorf("got %q; want 5 elements including the empty one", got)
+    }
+
+    if got := GetArrayString("TEST_PIPELINE_MISSING", Trim()); got != nil {
+        t.Errorf("got %q; want nil", got)
+    }
+}
@@ -0,0 +1,74 @@
+package env
+
+import (
+	"fmt"
+	"iter"
+	"strings"
+)
+
+// OrderedMap is a string map that remembers the order in which keys were
+// first set. The zero value is an empty map ready to use.
+type OrderedMap struct {
+	keys   []string
+	values map[string]string
+}
+
+// Set stores val under key. A new key is appended to the order; setting an
+// existing key replaces its value but keeps its position.
+func (m *OrderedMap) Set(key, val string) {
+	if m.values == nil {
+		m.values = make(map[string]string)
+	}
+	if _, exists := m.values[key]; !exists {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = val
+}
+
+// Get returns the value stored under key and whether it is present.
+func (m *OrderedMap) Get(key string) (string, bool) {
+	val, ok := m.values[key]
+	return val, ok
+}
+
+// Len returns the number of keys in the map.
+func (m *OrderedMap) Len() int {
+	return len(m.keys)
+}
+
+// Keys returns the keys in insertion order.
+func (m *OrderedMap) Keys() []string {
+	return append([]string(nil), m.keys...)
+}
+
+// All returns an iterator over the key-value pairs in insertion order.
+func (m *OrderedMap) All() iter.Seq2[string, string] {
+	return func(yield func(string, string) bool) {
+		for _, key := range m.keys {
+			if !yield(key, m.values[key]) {
+				return
+			}
+		}
+	}
+}
+
+// GetEnvOrderedMap retrieves an environment variable as an OrderedMap that
+// preserves the order entries are declared in, e.g. for middleware chains
+// such as MIDDLEWARE=auth:strict,log:json,gzip:on. A key declared twice keeps
+// its first position and its last value. Panics if any entry doesn't contain
+// the key-value delimiter.
+func GetEnvOrderedMap(key, entryDelim, kvDelim string, defaultValue *OrderedMap) *OrderedMap {
+	if val := GetEnvString(key, ""); val != "" {
+		result := &OrderedMap{}
+		for _, entry := range strings.Split(val, entryDelim) {
+			kv := strings.SplitN(entry, kvDelim, 2)
+			if len(kv) != 2 {
+				parseFailed(key, fmt.Errorf("Environment variable %s contains invalid map entry: %s", key, entry))
+				return defaultValue
+			}
+			result.Set(strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1]))
+		}
+		return result
+	}
+	return defaultValue
+}
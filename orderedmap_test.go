@@ -0,0 +1,64 @@
+package env
+
+import (
+    "os"
+    "reflect"
+    "testing"
+)
+
+// Test that OrderedMap keeps first-insertion order
+func TestOrderedMap(t *testing.T) {
+    var m OrderedMap
+    m.Set("b", "1")
+    m.Set("a", "2")
+    m.Set("b", "3")
+
+    if got := m.Keys(); !reflect.DeepEqual(got, []string{"b", "a"}) {
+        t.Errorf("got %v; want [b a]", got)
+    }
+    if val, ok := m.Get("b"); !ok || val != "3" {
+        t.Errorf("got (%q, %v); want (\"3\", true)", val, ok)
+    }
+    if _, ok := m.Get("missing"); ok {
+        t.Errorf("expected missing key to be absent")
+    }
+    if m.Len() != 2 {
+        t.Errorf("got %d; want 2", m.Len())
+    }
+}
+
+// Test that GetEnvOrderedMap iterates in the declared order
+func TestGetEnvOrderedMap(t *testing.T) {
+    os.Setenv("TEST_ORDERED_MAP", "zeta:1, auth:strict,log:json,alpha:2")
+    defer os.Unsetenv("TEST_ORDERED_MAP")
+
+    got := GetEnvOrderedMap("TEST_ORDERED_MAP", ",", ":", nil)
+    var keys, values []string
+    for k, v := range got.All() {
+        keys = append(keys, k)
+        values = append(values, v)
+    }
+    if !reflect.DeepEqual(keys, []string{"zeta", "auth", "log", "alpha"}) {
+        t.Errorf("got keys %v; want [zeta auth log alpha]", keys)
+    }
+    if !reflect.DeepEqual(values, []string{"1", "strict", "json", "2"}) {
+        t.Errorf("got values %v; want [1 strict json 2]", values)
+    }
+
+    // Test default return
+    os.Unsetenv("TEST_ORDERED_MAP")
+    def := &OrderedMap{}
+    def.Set("default", "value")
+    if gotDef := GetEnvOrderedMap("TEST_ORDERED_MAP", ",", ":", def); gotDef != def {
+        t.Errorf("expected default value to be returned, got %v", gotDef)
+    }
+
+    // A malformed entry panics
+    os.Setenv("TEST_ORDERED_MAP", "auth:strict,broken")
+    defer func() {
+        if recover() == nil {
+            t.Errorf("expected panic on malformed entry")
+        }
+    }()
+    GetEnvOrderedMap("TEST_ORDERED_MAP", ",", ":", nil)
+}
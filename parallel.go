@@ -0,0 +1,112 @@
+package env
+
+import (
+	"bytes"
+	"os"
+	"sort"
+	"sync"
+)
+
+// parseWorkers is the number of goroutines used to parse each .env file.
+// Values below 2 select the sequential line-by-line parser.
+var parseWorkers int
+
+// SetParseWorkers makes Load parse each .env file in n chunks concurrently,
+// which can speed up startup with very large generated files. The file is
+// split on line boundaries and the chunks are merged in file order, so a key
+// defined twice still takes its last value. Pass 0 or 1 to restore the
+// default sequential parser.
+func SetParseWorkers(n int) {
+	parseWorkers = n
+}
+
+// envLine is a key/value pair parsed from a single .env line, with the line
+// itself and its number relative to the chunk.
+type envLine struct {
+	key, val, text string
+	line           int
+}
+
+// loadFileParallel reads file into memory, parses it in up to workers chunks
+// concurrently and merges the results into dst in file order. It returns the
+// malformed lines it skipped; unreadable files are skipped entirely.
+func loadFileParallel(file string, dst map[string]string, workers int) []ParseError {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+
+	chunks := splitLines(data, workers)
+	results := make([][]envLine, len(chunks))
+	skipped := make([][]ParseError, len(chunks))
+	lines := make([]int, len(chunks))
+	var wg sync.WaitGroup
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for len(chunk) > 0 {
+				line := chunk
+				if j := bytes.IndexByte(chunk, '\n'); j >= 0 {
+					line, chunk = chunk[:j], chunk[j+1:]
+				} else {
+					chunk = nil
+				}
+				line = bytes.TrimSuffix(line, []byte("\r"))
+				lines[i]++
+				key, val, ok, reason := parseLine(string(line))
+				if reason != "" {
+					// Line numbers are relative to the chunk until merged
+					skipped[i] = append(skipped[i], ParseError{File: file, Line: lines[i], Text: string(line), Reason: reason})
+				} else if ok {
+					results[i] = append(results[i], envLine{key, val, string(line), lines[i]})
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Merge in chunk order so later definitions override earlier ones
+	var errs []ParseError
+	offset := 0
+	for i := range chunks {
+		start := len(errs)
+		for _, line := range results[i] {
+			if reason := storeValue(dst, line.key, line.val); reason != "" {
+				errs = append(errs, ParseError{File: file, Line: line.line + offset, Text: line.text, Reason: reason})
+			}
+		}
+		for _, e := range skipped[i] {
+			e.Line += offset
+			errs = append(errs, e)
+		}
+		// Interleave duplicates with malformed lines as the sequential parser does
+		sort.Slice(errs[start:], func(a, b int) bool {
+			return errs[start+a].Line < errs[start+b].Line
+		})
+		offset += lines[i]
+	}
+	return errs
+}
+
+// splitLines splits data into at most n chunks of roughly equal size, each
+// ending just after a newline (except possibly the last).
+func splitLines(data []byte, n int) [][]byte {
+	var chunks [][]byte
+	size := len(data)/n + 1
+	for len(data) > 0 {
+		if len(data) <= size {
+			chunks = append(chunks, data)
+			break
+		}
+		end := size
+		if i := bytes.IndexByte(data[end:], '\n'); i >= 0 {
+			end += i + 1
+		} else {
+			end = len(data)
+		}
+		chunks = append(chunks, data[:end])
+		data = data[end:]
+	}
+	return chunks
+}
@@ -0,0 +1,101 @@
+package env
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+// writeLargeEnv writes a generated .env file with n keys, comments, blank
+// lines and redefinitions to dir and returns its path.
+func writeLargeEnv(tb testing.TB, dir string, n int) string {
+    var b strings.Builder
+    for i := 0; i < n; i++ {
+        switch i % 10 {
+        case 0:
+            b.WriteString("# comment\n\n")
+        case 5:
+            b.WriteString("malformed line\n")
+        }
+        fmt.Fprintf(&b, "KEY_%d = value %d\n", i%(n/2+1), i)
+    }
+    file := filepath.Join(dir, "large.env")
+    if err := os.WriteFile(file, []byte(b.String()), 0o644); err != nil {
+        tb.Fatalf("WriteFile failed: %v", err)
+    }
+    return file
+}
+
+// Test that the parallel parser yields the same values as the sequential one
+func TestLoadFileParallel(t *testing.T) {
+    file := writeLargeEnv(t, t.TempDir(), 10000)
+
+    want := make(map[string]string)
+    wantSkipped := loadFile(file, want)
+
+    for _, workers := range []int{2, 3, 8, 64} {
+        got := make(map[string]string)
+        gotSkipped := loadFileParallel(file, got, workers)
+        if !reflect.DeepEqual(got, want) {
+            t.Errorf("workers=%d: results differ from the sequential parser", workers)
+        }
+        if !reflect.DeepEqual(gotSkipped, wantSkipped) {
+            t.Errorf("workers=%d: skipped lines differ from the sequential parser", workers)
+        }
+    }
+
+    // Redefined keys keep the last value in file order
+    if want["KEY_0"] != "value 5001" {
+        t.Errorf("got %q; want %q", want["KEY_0"], "value 5001")
+    }
+
+    // Strict duplicates are reported at the same lines by both parsers
+    defer SetStrictDuplicates(false)
+    SetStrictDuplicates(true)
+    want = make(map[string]string)
+    wantSkipped = loadFile(file, want)
+    for _, workers := range []int{2, 3, 8, 64} {
+        got := make(map[string]string)
+        gotSkipped := loadFileParallel(file, got, workers)
+        if !reflect.DeepEqual(got, want) || !reflect.DeepEqual(gotSkipped, wantSkipped) {
+            t.Errorf("workers=%d: strict results differ from the sequential parser", workers)
+        }
+    }
+}
+
+// Test that Load uses the parallel parser when workers are set
+func TestSetParseWorkers(t *testing.T) {
+    dir := t.TempDir()
+    defer Load(envDir)
+    defer SetParseWorkers(0)
+
+    os.WriteFile(filepath.Join(dir, "app.env"), []byte("TEST_PARALLEL=a\nTEST_PARALLEL=b\nTEST_PARALLEL_OTHER=c"), 0o644)
+    SetParseWorkers(4)
+    Load(dir)
+
+    if got := GetEnvString("TEST_PARALLEL", ""); got != "b" {
+        t.Errorf("got %q; want %q", got, "b")
+    }
+    if got := GetEnvString("TEST_PARALLEL_OTHER", ""); got != "c" {
+        t.Errorf("got %q; want %q", got, "c")
+    }
+}
+
+// Benchmark for parsing a large file line-by-line
+func BenchmarkLoadFile(b *testing.B) {
+    file := writeLargeEnv(b, b.TempDir(), 200000)
+    for i := 0; i < b.N; i++ {
+        loadFile(file, make(map[string]string))
+    }
+}
+
+// Benchmark for parsing a large file in parallel chunks
+func BenchmarkLoadFileParallel(b *testing.B) {
+    file := writeLargeEnv(b, b.TempDir(), 200000)
+    for i := 0; i < b.N; i++ {
+        loadFileParallel(file, make(map[string]string), 8)
+    }
+}
@@ -0,0 +1,60 @@
+package env
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// accessed is the set of keys looked up by getters, for Record.
+var accessed sync.Map
+
+// found is the set of variable names that lookups have resolved, after any
+// prefix, alias or normalization is applied, for UnusedKeys.
+var found sync.Map
+
+// Record writes the keys that getters have looked up so far, with their
+// currently resolved values, to w as KEY=VALUE lines sorted by key. Keys that
+// were looked up but are not set are left out. The output has the .env
+// format, so it can capture exactly the configuration that influenced a run
+// and be loaded again to reproduce it. Note that it contains secrets in
+// plain text if any were read.
+func Record(w io.Writer) error {
+	var keys []string
+	accessed.Range(func(key, _ any) bool {
+		keys = append(keys, key.(string))
+		return true
+	})
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		val, ok, err := resolve(key)
+		if err != nil || !ok {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%s=%s\n", key, val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UnusedKeys returns the names of all variables set in the OS environment or
+// loaded into memory that no getter has read so far, sorted. Called late in
+// a run, it points at dead config that can be pruned. Variables read under a
+// prefix or alias count as used.
+func UnusedKeys() []string {
+	var unused []string
+	for key := range environ() {
+		if _, ok := found.Load(key); ok {
+			continue
+		}
+		if _, ok := accessed.Load(key); ok {
+			continue
+		}
+		unused = append(unused, key)
+	}
+	sort.Strings(unused)
+	return unused
+}
@@ -0,0 +1,65 @@
+package env
+
+import (
+    "bytes"
+    "os"
+    "strings"
+    "testing"
+)
+
+// Test that Record writes only the keys read by getters
+func TestRecord(t *testing.T) {
+    os.Setenv("TEST_RECORD_READ", "yes")
+    os.Setenv("TEST_RECORD_UNREAD", "no")
+    defer os.Unsetenv("TEST_RECORD_READ")
+    defer os.Unsetenv("TEST_RECORD_UNREAD")
+
+    GetEnvString("TEST_RECORD_READ", "")
+    GetEnvInt("TEST_RECORD_MISSING", 0)
+
+    var buf bytes.Buffer
+    if err := Record(&buf); err != nil {
+        t.Fatalf("Record failed: %v", err)
+    }
+    out := buf.String()
+    if !strings.Contains(out, "TEST_RECORD_READ=yes\n") {
+        t.Errorf("output %q does not contain the read key", out)
+    }
+    if strings.Contains(out, "TEST_RECORD_UNREAD") {
+        t.Errorf("output %q contains a key that was never read", out)
+    }
+    if strings.Contains(out, "TEST_RECORD_MISSING") {
+        t.Errorf("output %q contains a key that is not set", out)
+    }
+}
+
+// Test that UnusedKeys reports only variables never read
+func TestUnusedKeys(t *testing.T) {
+    defer Reset()
+    os.Setenv("TEST_UNUSED_READ", "a")
+    os.Setenv("TEST_UNUSED_DEAD", "b")
+    os.Setenv("TEST_UNUSED_PREFIXED", "c")
+    defer os.Unsetenv("TEST_UNUSED_READ")
+    defer os.Unsetenv("TEST_UNUSED_DEAD")
+    defer os.Unsetenv("TEST_UNUSED_PREFIXED")
+
+    GetEnvString("TEST_UNUSED_READ", "")
+    SetPrefix("TEST_UNUSED_")
+    GetEnvString("PREFIXED", "")
+
+    unused := UnusedKeys()
+    contains := func(key string) bool {
+        for _, k := range unused {
+            if k == key {
+                return true
+            }
+        }
+        return false
+    }
+    if !contains("TEST_UNUSED_DEAD") {
+        t.Errorf("expected TEST_UNUSED_DEAD to be reported")
+    }
+    if contains("TEST_UNUSED_READ") || contains("TEST_UNUSED_PREFIXED") {
+        t.Errorf("got %v; want read keys excluded", unused)
+    }
+}
@@ -0,0 +1,64 @@
+package env
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RequireGroup checks that keys are set all together or not at all, e.g.
+// SMTP_HOST, SMTP_USER and SMTP_PASS. It returns an error naming the missing
+// keys if only some of them are set.
+func RequireGroup(keys ...string) error {
+	var missing []string
+	for _, key := range keys {
+		if _, ok := lookup(key); !ok {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 && len(missing) < len(keys) {
+		return fmt.Errorf("environment variables %s must be set together, missing %s",
+			strings.Join(keys, ", "), strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// RequireExclusive checks that at most one of keys is set, e.g. USE_TLS and
+// USE_PLAINTEXT. It returns an error naming the conflicting keys otherwise.
+func RequireExclusive(keys ...string) error {
+	var present []string
+	for _, key := range keys {
+		if _, ok := lookup(key); ok {
+			present = append(present, key)
+		}
+	}
+	if len(present) > 1 {
+		return fmt.Errorf("environment variables %s are mutually exclusive", strings.Join(present, ", "))
+	}
+	return nil
+}
+
+// RequireAnyOf checks that at least one of keys is set, e.g. when at least
+// one authentication method must be configured.
+func RequireAnyOf(keys ...string) error {
+	for _, key := range keys {
+		if _, ok := lookup(key); ok {
+			return nil
+		}
+	}
+	return fmt.Errorf("at least one of environment variables %s must be set", strings.Join(keys, ", "))
+}
+
+// AssertRequired panics if any of keys is not set, listing all missing keys
+// at once. It is meant to be called at startup with the manifest of variables
+// the program cannot run without, e.g. from an init function.
+func AssertRequired(keys ...string) {
+	var missing []string
+	for _, key := range keys {
+		if _, ok := lookup(key); !ok {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		panic(fmt.Sprintf("Required environment variables are not set: %s", strings.Join(missing, ", ")))
+	}
+}
@@ -0,0 +1,91 @@
+package env
+
+import (
+    "os"
+    "strings"
+    "testing"
+)
+
+// Test for all-or-nothing groups of variables
+func TestRequireGroup(t *testing.T) {
+    keys := []string{"TEST_SMTP_HOST", "TEST_SMTP_USER", "TEST_SMTP_PASS"}
+    defer func() {
+        for _, key := range keys {
+            os.Unsetenv(key)
+        }
+    }()
+
+    // None set is fine
+    if err := RequireGroup(keys...); err != nil {
+        t.Errorf("unexpected error with no keys set: %v", err)
+    }
+
+    // Partially set is an error
+    os.Setenv("TEST_SMTP_HOST", "smtp.local")
+    if err := RequireGroup(keys...); err == nil {
+        t.Errorf("expected error with only some keys set")
+    }
+
+    // All set is fine
+    os.Setenv("TEST_SMTP_USER", "user")
+    os.Setenv("TEST_SMTP_PASS", "pass")
+    if err := RequireGroup(keys...); err != nil {
+        t.Errorf("unexpected error with all keys set: %v", err)
+    }
+}
+
+// Test for mutually exclusive variables
+func TestRequireExclusive(t *testing.T) {
+    defer os.Unsetenv("TEST_USE_TLS")
+    defer os.Unsetenv("TEST_USE_PLAINTEXT")
+
+    if err := RequireExclusive("TEST_USE_TLS", "TEST_USE_PLAINTEXT"); err != nil {
+        t.Errorf("unexpected error with no keys set: %v", err)
+    }
+
+    os.Setenv("TEST_USE_TLS", "true")
+    if err := RequireExclusive("TEST_USE_TLS", "TEST_USE_PLAINTEXT"); err != nil {
+        t.Errorf("unexpected error with one key set: %v", err)
+    }
+
+    os.Setenv("TEST_USE_PLAINTEXT", "true")
+    if err := RequireExclusive("TEST_USE_TLS", "TEST_USE_PLAINTEXT"); err == nil {
+        t.Errorf("expected error with both keys set")
+    }
+}
+
+// Test for requiring at least one of several variables
+func TestRequireAnyOf(t *testing.T) {
+    defer os.Unsetenv("TEST_AUTH_TOKEN")
+
+    if err := RequireAnyOf("TEST_AUTH_TOKEN", "TEST_AUTH_PASSWORD"); err == nil {
+        t.Errorf("expected error with no keys set")
+    }
+
+    os.Setenv("TEST_AUTH_TOKEN", "secret")
+    if err := RequireAnyOf("TEST_AUTH_TOKEN", "TEST_AUTH_PASSWORD"); err != nil {
+        t.Errorf("unexpected error with one key set: %v", err)
+    }
+}
+
+// Test for asserting that required variables are set
+func TestAssertRequired(t *testing.T) {
+    os.Setenv("TEST_REQUIRED_A", "a")
+    defer os.Unsetenv("TEST_REQUIRED_A")
+
+    // All present does not panic
+    AssertRequired("TEST_REQUIRED_A")
+
+    // The panic lists every missing key
+    defer func() {
+        r := recover()
+        if r == nil {
+            t.Fatalf("expected panic with missing keys")
+        }
+        msg := r.(string)
+        if !strings.Contains(msg, "TEST_REQUIRED_B, TEST_REQUIRED_C") || strings.Contains(msg, "TEST_REQUIRED_A") {
+            t.Errorf("got %q; want both missing keys and not the present one", msg)
+        }
+    }()
+    AssertRequired("TEST_REQUIRED_A", "TEST_REQUIRED_B", "TEST_REQUIRED_C")
+}
@@ -0,0 +1,264 @@
+package env
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// GetEnvJSONValidated retrieves an environment variable holding a JSON
+// document, validates it against the JSON Schema in schema and unmarshals it
+// into a T. Validation catches structural problems such as missing or
+// mistyped fields before they reach the program.
+//
+// The supported keywords are type, enum, const, properties, required,
+// additionalProperties, items, minItems, maxItems, minLength, maxLength,
+// pattern, minimum, maximum, exclusiveMinimum and exclusiveMaximum, plus
+// annotations such as title and description. Panics listing every violation
+// if the document does not conform, or if it is not valid JSON. An invalid
+// schema, including one using any other keyword such as $ref or allOf, the
+// tuple form of items or a pattern that does not compile, is a programming
+// error and always panics, since ignoring it would let non-conforming
+// documents through.
+func GetEnvJSONValidated[T any](key string, schema string, defaultValue T) T {
+	var s any
+	if err := json.Unmarshal([]byte(schema), &s); err != nil {
+		panic(fmt.Sprintf("Invalid JSON schema for environment variable %s: %v", key, err))
+	}
+	if err := checkSchema(s, ""); err != nil {
+		panic(fmt.Sprintf("Invalid JSON schema for environment variable %s: %v", key, err))
+	}
+
+	if val := GetEnvString(key, ""); val != "" {
+		var doc any
+		if err := json.Unmarshal([]byte(val), &doc); err != nil {
+			parseFailed(key, fmt.Errorf("Environment variable %s is not valid JSON: %v", key, err))
+			return defaultValue
+		}
+		if violations := validateSchema(s, doc, ""); len(violations) > 0 {
+			parseFailed(key, fmt.Errorf("Environment variable %s does not match its schema: %s", key, strings.Join(violations, "; ")))
+			return defaultValue
+		}
+		var result T
+		if err := json.Unmarshal([]byte(val), &result); err != nil {
+			parseFailed(key, fmt.Errorf("Environment variable %s could not be decoded: %v", key, err))
+			return defaultValue
+		}
+		return result
+	}
+	return defaultValue
+}
+
+// schemaKeywords lists the keywords validateSchema understands, along with
+// annotations that never affect validation.
+var schemaKeywords = map[string]bool{
+	"type": true, "enum": true, "const": true, "properties": true, "required": true,
+	"additionalProperties": true, "items": true, "minItems": true, "maxItems": true,
+	"minLength": true, "maxLength": true, "pattern": true, "minimum": true,
+	"maximum": true, "exclusiveMinimum": true, "exclusiveMaximum": true,
+
+	"$schema": true, "$id": true, "$comment": true, "title": true, "description": true,
+	"default": true, "examples": true, "deprecated": true, "readOnly": true, "writeOnly": true,
+}
+
+// checkSchema walks schema and returns an error for the first keyword or
+// form that validateSchema does not support, or an invalid pattern, naming
+// it with its JSON pointer.
+func checkSchema(schema any, path string) error {
+	s, ok := schema.(map[string]any)
+	if !ok {
+		return nil
+	}
+	for keyword := range s {
+		if !schemaKeywords[keyword] {
+			return fmt.Errorf("unsupported keyword %q at %s", keyword, at(path))
+		}
+	}
+
+	// The tuple form of items (an array of schemas) is not supported
+	if items, ok := s["items"]; ok {
+		if _, isObject := items.(map[string]any); !isObject {
+			if _, isBool := items.(bool); !isBool {
+				return fmt.Errorf("unsupported items form at %s: must be a schema object or boolean", at(path))
+			}
+		}
+	}
+	if pattern, ok := s["pattern"]; ok {
+		str, isString := pattern.(string)
+		if !isString {
+			return fmt.Errorf("pattern at %s must be a string", at(path))
+		}
+		if _, err := regexp.Compile(str); err != nil {
+			return fmt.Errorf("invalid pattern %q at %s: %v", str, at(path), err)
+		}
+	}
+	if props, ok := s["properties"].(map[string]any); ok {
+		for name, sub := range props {
+			if err := checkSchema(sub, path+"/properties/"+name); err != nil {
+				return err
+			}
+		}
+	}
+	if err := checkSchema(s["additionalProperties"], path+"/additionalProperties"); err != nil {
+		return err
+	}
+	return checkSchema(s["items"], path+"/items")
+}
+
+// validateSchema checks doc against schema and returns one message per
+// violation, each prefixed with the JSON pointer of the offending value.
+func validateSchema(schema, doc any, path string) []string {
+	s, ok := schema.(map[string]any)
+	if !ok {
+		// The boolean schemas true and false accept and reject everything
+		if b, isBool := schema.(bool); isBool && !b {
+			return []string{at(path) + ": not allowed"}
+		}
+		return nil
+	}
+
+	var violations []string
+	fail := func(format string, args ...any) {
+		violations = append(violations, at(path)+": "+fmt.Sprintf(format, args...))
+	}
+
+	if t, ok := s["type"]; ok && !matchesType(t, doc) {
+		fail("expected type %v, got %s", t, jsonType(doc))
+		return violations
+	}
+	if enum, ok := s["enum"].([]any); ok {
+		found := false
+		for _, option := range enum {
+			if reflect.DeepEqual(option, doc) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("value is not one of %v", enum)
+		}
+	}
+	if c, ok := s["const"]; ok && !reflect.DeepEqual(c, doc) {
+		fail("value must be %v", c)
+	}
+
+	switch v := doc.(type) {
+	case map[string]any:
+		props, _ := s["properties"].(map[string]any)
+		if required, ok := s["required"].([]any); ok {
+			for _, name := range required {
+				if name, ok := name.(string); ok {
+					if _, present := v[name]; !present {
+						fail("missing required property %q", name)
+					}
+				}
+			}
+		}
+		for name, value := range v {
+			if sub, ok := props[name]; ok {
+				violations = append(violations, validateSchema(sub, value, path+"/"+name)...)
+			} else if additional, ok := s["additionalProperties"]; ok {
+				violations = append(violations, validateSchema(additional, value, path+"/"+name)...)
+			}
+		}
+	case []any:
+		if n, ok := number(s["minItems"]); ok && float64(len(v)) < n {
+			fail("expected at least %v items, got %d", n, len(v))
+		}
+		if n, ok := number(s["maxItems"]); ok && float64(len(v)) > n {
+			fail("expected at most %v items, got %d", n, len(v))
+		}
+		if items, ok := s["items"]; ok {
+			for i, item := range v {
+				violations = append(violations, validateSchema(items, item, fmt.Sprintf("%s/%d", path, i))...)
+			}
+		}
+	case string:
+		length := float64(utf8.RuneCountInString(v))
+		if n, ok := number(s["minLength"]); ok && length < n {
+			fail("expected at least %v characters", n)
+		}
+		if n, ok := number(s["maxLength"]); ok && length > n {
+			fail("expected at most %v characters", n)
+		}
+		if pattern, ok := s["pattern"].(string); ok {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				panic(fmt.Sprintf("Invalid JSON schema pattern %q: %v", pattern, err))
+			}
+			if !re.MatchString(v) {
+				fail("value does not match pattern %q", pattern)
+			}
+		}
+	case float64:
+		if n, ok := number(s["minimum"]); ok && v < n {
+			fail("value %v is less than the minimum %v", v, n)
+		}
+		if n, ok := number(s["maximum"]); ok && v > n {
+			fail("value %v is greater than the maximum %v", v, n)
+		}
+		if n, ok := number(s["exclusiveMinimum"]); ok && v <= n {
+			fail("value %v must be greater than %v", v, n)
+		}
+		if n, ok := number(s["exclusiveMaximum"]); ok && v >= n {
+			fail("value %v must be less than %v", v, n)
+		}
+	}
+	return violations
+}
+
+// matchesType reports whether doc has the JSON type t, which is either a
+// type name or a list of them.
+func matchesType(t, doc any) bool {
+	names, ok := t.([]any)
+	if !ok {
+		names = []any{t}
+	}
+	actual := jsonType(doc)
+	for _, name := range names {
+		if name == actual || (name == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType returns the JSON type name of a decoded value, distinguishing
+// integers from other numbers as JSON Schema does.
+func jsonType(doc any) string {
+	switch v := doc.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// number returns v as a float64 if it is a decoded JSON number.
+func number(v any) (float64, bool) {
+	n, ok := v.(float64)
+	return n, ok
+}
+
+// at returns path for use in messages, with the document root shown as "/".
+func at(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path
+}
@@ -0,0 +1,111 @@
+package env
+
+import (
+    "os"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+const testServiceSchema = `{
+    "type": "object",
+    "required": ["name", "replicas"],
+    "additionalProperties": false,
+    "properties": {
+        "name": {"type": "string", "minLength": 1, "pattern": "^[a-z-]+$"},
+        "replicas": {"type": "integer", "minimum": 1, "maximum": 10},
+        "mode": {"enum": ["active", "standby"]},
+        "ports": {"type": "array", "minItems": 1, "items": {"type": "integer"}}
+    }
+}`
+
+type testService struct {
+    Name     string `json:"name"`
+    Replicas int    `json:"replicas"`
+    Mode     string `json:"mode"`
+    Ports    []int  `json:"ports"`
+}
+
+// Test for decoding a JSON document that conforms to its schema
+func TestGetEnvJSONValidated(t *testing.T) {
+    os.Setenv("TEST_JSON_SERVICE", `{"name":"api","replicas":3,"mode":"active","ports":[80,443]}`)
+    defer os.Unsetenv("TEST_JSON_SERVICE")
+
+    got := GetEnvJSONValidated("TEST_JSON_SERVICE", testServiceSchema, testService{})
+    want := testService{Name: "api", Replicas: 3, Mode: "active", Ports: []int{80, 443}}
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("got %+v; want %+v", got, want)
+    }
+
+    def := testService{Name: "default"}
+    if got := GetEnvJSONValidated("TEST_JSON_SERVICE_MISSING", testServiceSchema, def); !reflect.DeepEqual(got, def) {
+        t.Errorf("got %+v; want default %+v", got, def)
+    }
+}
+
+// Test that a non-conforming document panics listing every violation
+func TestGetEnvJSONValidatedInvalid(t *testing.T) {
+    os.Setenv("TEST_JSON_SERVICE", `{"name":"API","replicas":1.5,"mode":"idle","ports":["http"],"extra":true}`)
+    defer os.Unsetenv("TEST_JSON_SERVICE")
+
+    defer func() {
+        r := recover()
+        if r == nil {
+            t.Fatalf("expected panic for a non-conforming document")
+        }
+        msg := r.(string)
+        for _, want := range []string{"/name", "/replicas", "/mode", "/ports/0", "/extra"} {
+            if !strings.Contains(msg, want) {
+                t.Errorf("panic %q does not mention %s", msg, want)
+            }
+        }
+    }()
+    GetEnvJSONValidated("TEST_JSON_SERVICE", testServiceSchema, testService{})
+}
+
+// Test that missing required properties are reported
+func TestValidateSchemaRequired(t *testing.T) {
+    var reported error
+    SetErrorHandler(func(key string, err error) { reported = err })
+    defer Reset()
+
+    os.Setenv("TEST_JSON_SERVICE", `{"mode":"standby"}`)
+    defer os.Unsetenv("TEST_JSON_SERVICE")
+    got := GetEnvJSONValidated("TEST_JSON_SERVICE", testServiceSchema, testService{Name: "fallback"})
+    if got.Name != "fallback" {
+        t.Errorf("got %+v; want the default", got)
+    }
+    if reported == nil || !strings.Contains(reported.Error(), `"name"`) || !strings.Contains(reported.Error(), `"replicas"`) {
+        t.Errorf("got %v; want both missing properties reported", reported)
+    }
+}
+
+// Test that schemas using unsupported keywords or forms, or invalid patterns,
+// panic even without a value
+func TestGetEnvJSONValidatedUnsupportedKeyword(t *testing.T) {
+    schemas := map[string]string{
+        "$ref":          `{"$ref":"#/definitions/service"}`,
+        "allOf":         `{"type":"object","allOf":[{"required":["name"]}]}`,
+        "minProperties": `{"type":"object","properties":{"tags":{"type":"object","minProperties":1}}}`,
+        "multipleOf":    `{"type":"array","items":{"type":"integer","multipleOf":5}}`,
+        "items":         `{"type":"array","items":[{"type":"string"},{"type":"integer"}]}`,
+        "pattern":       `{"type":"object","properties":{"name":{"type":"string","pattern":"[a-z"}}}`,
+    }
+    for keyword, schema := range schemas {
+        func() {
+            defer func() {
+                r := recover()
+                if r == nil || !strings.Contains(r.(string), keyword) {
+                    t.Errorf("got panic %v; want one naming %s", r, keyword)
+                }
+            }()
+            GetEnvJSONValidated("TEST_JSON_UNSUPPORTED_MISSING", schema, 0)
+        }()
+    }
+
+    // Annotations are accepted
+    schema := `{"$schema":"https://json-schema.org/draft/2020-12/schema","title":"Port","type":"integer"}`
+    if got := GetEnvJSONValidated("TEST_JSON_UNSUPPORTED_MISSING", schema, 8080); got != 8080 {
+        t.Errorf("got %v; want %v", got, 8080)
+    }
+}
@@ -0,0 +1,59 @@
+package env
+
+import "sync"
+
+// subscription tracks the last value delivered for a key.
+type subscription struct {
+	key  string
+	last string
+	ch   chan string
+}
+
+var (
+	subscriptions   = make(map[*subscription]struct{})
+	subscriptionsMu sync.Mutex
+)
+
+// SubscribeString returns a channel that receives the current value of key
+// immediately and the new value whenever Load or Reload changes it. An unset
+// variable is reported as an empty string. The channel keeps only the latest
+// value, so slow receivers skip intermediate updates rather than blocking
+// reloads. The returned function unsubscribes and closes the channel.
+func SubscribeString(key string) (<-chan string, func()) {
+	sub := &subscription{key: key, last: GetEnvString(key, ""), ch: make(chan string, 1)}
+	sub.ch <- sub.last
+
+	subscriptionsMu.Lock()
+	subscriptions[sub] = struct{}{}
+	subscriptionsMu.Unlock()
+
+	var once sync.Once
+	return sub.ch, func() {
+		once.Do(func() {
+			subscriptionsMu.Lock()
+			delete(subscriptions, sub)
+			subscriptionsMu.Unlock()
+			close(sub.ch)
+		})
+	}
+}
+
+// notifySubscribers pushes changed values to all subscriptions.
+func notifySubscribers() {
+	subscriptionsMu.Lock()
+	defer subscriptionsMu.Unlock()
+	for sub := range subscriptions {
+		val := GetEnvString(sub.key, "")
+		if val == sub.last {
+			continue
+		}
+		sub.last = val
+
+		// Replace an undelivered value with the latest one
+		select {
+		case <-sub.ch:
+		default:
+		}
+		sub.ch <- val
+	}
+}
@@ -0,0 +1,49 @@
+package env
+
+import (
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+// Test that subscribers receive the current value and reload changes
+func TestSubscribeString(t *testing.T) {
+    dir := t.TempDir()
+    defer Load(envDir)
+
+    file := filepath.Join(dir, "app.env")
+    os.WriteFile(file, []byte("TEST_SUBSCRIBE=v1\n"), 0o644)
+    Load(dir)
+
+    ch, unsubscribe := SubscribeString("TEST_SUBSCRIBE")
+    if got := <-ch; got != "v1" {
+        t.Errorf("got %q; want initial value %q", got, "v1")
+    }
+
+    // A reload without changes sends nothing
+    Reload()
+    select {
+    case got := <-ch:
+        t.Errorf("unexpected value %q for unchanged reload", got)
+    default:
+    }
+
+    // A reload with a changed value pushes it
+    os.WriteFile(file, []byte("TEST_SUBSCRIBE=v2\n"), 0o644)
+    Reload()
+    select {
+    case got := <-ch:
+        if got != "v2" {
+            t.Errorf("got %q; want %q", got, "v2")
+        }
+    default:
+        t.Errorf("expected a value after reload")
+    }
+
+    // Unsubscribing closes the channel
+    unsubscribe()
+    unsubscribe()
+    if _, ok := <-ch; ok {
+        t.Errorf("expected closed channel after unsubscribe")
+    }
+}